@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"atena_printer/internal/addressdb"
+	"atena_printer/internal/config"
+	"atena_printer/internal/model"
+	"atena_printer/internal/postal"
+	"atena_printer/internal/source"
+)
+
+// contactFlags は add / edit で宛先の項目を指定するフラグ。
+type contactFlags struct {
+	family, given, reading, joint, honorific *string
+	postal, address1, address2, tags         *string
+}
+
+func addContactFlags(fs *flag.FlagSet) *contactFlags {
+	return &contactFlags{
+		family:    fs.String("family", "", "姓"),
+		given:     fs.String("given", "", "名"),
+		reading:   fs.String("reading", "", "よみ"),
+		joint:     fs.String("joint", "", "連名 (カンマ区切り)"),
+		honorific: fs.String("honorific", "", "敬称 (空の場合は「様」)"),
+		postal:    fs.String("postal", "", "郵便番号"),
+		address1:  fs.String("address1", "", "住所1"),
+		address2:  fs.String("address2", "", "住所2"),
+		tags:      fs.String("tags", "", "タグ (カンマ区切り)"),
+	}
+}
+
+// apply は指定されたフラグだけを addr に反映する。
+func (f *contactFlags) apply(fs *flag.FlagSet, addr *model.Address) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "family":
+			addr.FamilyName = strings.TrimSpace(*f.family)
+		case "given":
+			addr.GivenName = strings.TrimSpace(*f.given)
+		case "reading":
+			addr.Reading = strings.TrimSpace(*f.reading)
+		case "joint":
+			addr.JointNames = model.SplitList(*f.joint)
+		case "honorific":
+			addr.Honorific = strings.TrimSpace(*f.honorific)
+		case "postal":
+			code, _, err := postal.Parse(*f.postal)
+			if err != nil {
+				exitError(err)
+			}
+			addr.PostalCode = code
+		case "address1":
+			addr.Address1 = strings.TrimSpace(*f.address1)
+		case "address2":
+			addr.Address2 = strings.TrimSpace(*f.address2)
+		case "tags":
+			addr.Tags = model.SplitList(*f.tags)
+		}
+	})
+}
+
+// openAddressDB は設定の sqlite_file を開く。
+func openAddressDB(cfg *config.Config, command string) *addressdb.DB {
+	if cfg.SQLiteFile == "" {
+		exitError(fmt.Errorf("%s は sqlite_file を設定した場合のみ使えます", command))
+	}
+	db, err := addressdb.Open(cfg.SQLiteFile)
+	if err != nil {
+		exitError(err)
+	}
+	return db
+}
+
+// findContact は ID か名前で宛先を1件選ぶ。名前があいまいな場合は候補から選んでもらう。
+func findContact(db *addressdb.DB, query string, answers *bufio.Reader) (model.Address, model.History) {
+	addresses, histories, err := db.List()
+	if err != nil {
+		exitError(err)
+	}
+	for _, addr := range addresses {
+		if addr.ID == query {
+			return addr, histories[addr.Row]
+		}
+	}
+	idx, ok := chooseCandidate(query, addresses, answers)
+	if !ok {
+		exitError(fmt.Errorf("「%s」に該当する宛先がありません", query))
+	}
+	return addresses[idx], histories[addresses[idx].Row]
+}
+
+func cmdAdd(args []string) {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	cf := addContactFlags(fs)
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	db := openAddressDB(cfg, "add")
+	defer db.Close()
+
+	var addr model.Address
+	cf.apply(fs, &addr)
+	if addr.FamilyName == "" {
+		exitError(fmt.Errorf("-family (姓) を指定してください"))
+	}
+
+	id, err := db.Add(addr, nil)
+	if err != nil {
+		exitError(err)
+	}
+	fmt.Printf("%s %s を追加しました (ID: %s)\n", addr.FamilyName, addr.GivenName, id)
+}
+
+func cmdEdit(args []string) {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	cf := addContactFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		exitError(fmt.Errorf("編集する宛先の ID か名前を1つ指定してください"))
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	db := openAddressDB(cfg, "edit")
+	defer db.Close()
+
+	addr, _ := findContact(db, fs.Arg(0), bufio.NewReader(os.Stdin))
+	before := addr
+	cf.apply(fs, &addr)
+	if addr.FamilyName == "" {
+		exitError(fmt.Errorf("姓を空にはできません"))
+	}
+
+	if err := db.Update(addr); err != nil {
+		exitError(err)
+	}
+	fmt.Printf("%s %s (ID: %s) を更新しました\n", before.FamilyName, before.GivenName, addr.ID)
+	printContact(addr, nil)
+}
+
+func cmdRemove(args []string) {
+	fs := flag.NewFlagSet("remove", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	yes := fs.Bool("yes", false, "確認せずに削除する")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		exitError(fmt.Errorf("削除する宛先の ID か名前を1つ指定してください"))
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	db := openAddressDB(cfg, "remove")
+	defer db.Close()
+
+	answers := bufio.NewReader(os.Stdin)
+	addr, history := findContact(db, fs.Arg(0), answers)
+	printContact(addr, history)
+	if !*yes {
+		fmt.Print("この宛先を年ステータスの記録ごと削除しますか? [y/N]: ")
+		line, _ := answers.ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(line)); a != "y" && a != "yes" {
+			fmt.Println("削除しませんでした。")
+			return
+		}
+	}
+
+	if err := db.Remove(addr.ID); err != nil {
+		exitError(err)
+	}
+	fmt.Printf("%s %s (ID: %s) を削除しました\n", addr.FamilyName, addr.GivenName, addr.ID)
+}
+
+func cmdShow(args []string) {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		exitError(fmt.Errorf("表示する宛先の ID か名前を1つ指定してください"))
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	db := openAddressDB(cfg, "show")
+	defer db.Close()
+
+	addr, history := findContact(db, fs.Arg(0), bufio.NewReader(os.Stdin))
+	printContact(addr, history)
+}
+
+func printContact(addr model.Address, history model.History) {
+	fmt.Printf("  ID:       %s\n", addr.ID)
+	fmt.Printf("  氏名:     %s %s %s\n", addr.FamilyName, addr.GivenName, addr.Honorific)
+	if addr.Reading != "" {
+		fmt.Printf("  よみ:     %s\n", addr.Reading)
+	}
+	if len(addr.JointNames) > 0 {
+		fmt.Printf("  連名:     %s\n", strings.Join(addr.JointNames, "、"))
+	}
+	fmt.Printf("  住所:     〒%s %s %s\n", formatPostalCode(addr.PostalCode), addr.Address1, addr.Address2)
+	if len(addr.Tags) > 0 {
+		fmt.Printf("  タグ:     %s\n", strings.Join(addr.Tags, "、"))
+	}
+	for _, year := range addressdb.Years(history) {
+		if st := history[year]; st.Sent || st.Received || st.Mourning {
+			fmt.Printf("  %d年:   %s\n", year, statusLabel(st))
+		}
+	}
+}
+
+func cmdDBImport(args []string) {
+	fs := flag.NewFlagSet("db-import", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	appendMode := fs.Bool("append", false, "登録済みの宛先があっても追加する")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	db := openAddressDB(cfg, "db-import")
+	defer db.Close()
+
+	// 取り込み元: 引数のファイル、無ければ設定の tsv_file / spreadsheet_id など (sqlite_file 以外)
+	from := *cfg
+	from.SQLiteFile = ""
+	if from.Source == "sqlite" {
+		from.Source = ""
+	}
+	switch {
+	case fs.NArg() == 1:
+		from.Source = "file"
+		from.AddressFile = fs.Arg(0)
+	case fs.NArg() > 1:
+		exitError(fmt.Errorf("取り込むファイルは1つだけ指定してください"))
+	case from.TSVFile == "" && from.AddressFile == "" && from.SpreadsheetID == "":
+		exitError(fmt.Errorf("取り込み元がありません。ファイルを指定するか、設定に tsv_file などを残してください"))
+	}
+
+	if n, err := db.Count(); err != nil {
+		exitError(err)
+	} else if n > 0 && !*appendMode {
+		exitError(fmt.Errorf("%s には既に %d件の宛先があります。追加で取り込む場合は -append を指定してください", db.Path(), n))
+	}
+
+	src, err := source.Open(&from)
+	if err != nil {
+		exitError(err)
+	}
+	addresses, histories, err := src.ReadHistory()
+	if err != nil {
+		exitError(err)
+	}
+
+	n, err := db.Import(addresses, histories)
+	if err != nil {
+		exitError(err)
+	}
+	fmt.Printf("%s から %d件を %s に取り込みました。\n", src.Describe(), n, db.Path())
+}
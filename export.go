@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/filter"
+	"atena_printer/internal/model"
+	"atena_printer/internal/source"
+	"atena_printer/internal/vcard"
+)
+
+func cmdExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	format := fs.String("format", "vcf", "出力形式 (vcf)")
+	whereExpr := fs.String("where", "", "絞り込み式")
+	output := fs.String("output", "", "出力ファイルパス (空の場合は標準出力)")
+	fs.Parse(args)
+
+	if *format != "vcf" {
+		exitError(fmt.Errorf("不明な -format: %s (vcf を指定してください)", *format))
+	}
+
+	where, err := filter.Parse(*whereExpr)
+	if err != nil {
+		exitError(err)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+
+	client, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+
+	all, histories, err := client.ReadHistory()
+	if err != nil {
+		exitError(err)
+	}
+
+	var addresses []model.Address
+	for _, addr := range all {
+		if where.Eval(filter.Env{Address: addr, History: histories[addr.Row], Year: cfg.Year}) {
+			addresses = append(addresses, addr)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if *output != "" {
+		f, err = os.Create(*output)
+		if err != nil {
+			exitError(fmt.Errorf("出力ファイルの作成に失敗: %w", err))
+		}
+		w = f
+	}
+
+	if err := vcard.Write(w, addresses); err != nil {
+		if f != nil {
+			f.Close()
+		}
+		exitError(fmt.Errorf("vCard の書き出しに失敗: %w", err))
+	}
+	if f != nil {
+		// 書き込みエラーが Close で初めて分かる場合があるので確認する
+		if err := f.Close(); err != nil {
+			exitError(fmt.Errorf("vCard の書き出しに失敗: %w", err))
+		}
+		fmt.Fprintf(os.Stderr, "%d件を %s に書き出しました。\n", len(addresses), *output)
+	}
+}
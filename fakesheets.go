@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"atena_printer/internal/sheets"
+)
+
+func cmdFakeSheets(args []string) {
+	fs := flag.NewFlagSet("fake-sheets", flag.ExitOnError)
+	tsvFile := fs.String("tsv", "", "シートの内容として使う TSV ファイル (書き込みはこのファイルに反映される)")
+	sheetName := fs.String("sheet", "住所録", "シート名")
+	addr := fs.String("addr", "127.0.0.1:8089", "待ち受けるアドレス")
+	credentials := fs.String("write-credentials", "", "このサーバ用のサービスアカウント認証ファイルを書き出すパス")
+	oauthClient := fs.String("write-oauth-client", "", "このサーバの login 用の OAuth クライアントファイルを書き出すパス")
+	failRate := fs.Float64("fail-rate", 0, "503 で失敗させるリクエストの割合 (0〜1、再試行の確認用)")
+	fs.Parse(args)
+
+	if *tsvFile == "" {
+		exitError(fmt.Errorf("-tsv で TSV ファイルを指定してください"))
+	}
+	if _, err := os.Stat(*tsvFile); err != nil {
+		exitError(fmt.Errorf("TSVファイルを開けません: %w", err))
+	}
+	if *failRate < 0 || *failRate > 1 {
+		exitError(fmt.Errorf("-fail-rate には 0〜1 を指定してください"))
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		exitError(fmt.Errorf("%s で待ち受けできません: %w", *addr, err))
+	}
+	base := "http://" + ln.Addr().String()
+
+	if *credentials != "" {
+		if err := sheets.WriteFakeCredentials(*credentials, base+"/token"); err != nil {
+			exitError(err)
+		}
+		fmt.Printf("認証ファイル %s を作成しました。\n", *credentials)
+	}
+
+	if *oauthClient != "" {
+		if err := sheets.WriteFakeOAuthClient(*oauthClient, base); err != nil {
+			exitError(err)
+		}
+		fmt.Printf("OAuth クライアントファイル %s を作成しました。\n", *oauthClient)
+	}
+
+	credPath := *credentials
+	if credPath == "" {
+		credPath = "(-write-credentials で作成したファイル)"
+	}
+	fmt.Printf(`%s をシート「%s」として %s で公開しています (Ctrl+C で終了)。
+設定ファイルの tsv_file / address_file / sqlite_file を外し、次の項目を設定してください:
+
+  "spreadsheet_id": "fake",
+  "sheet_name": %q,
+  "credentials_file": %q,
+  "endpoints": {
+    "sheets_api": "%s/v4/spreadsheets",
+    "token": "%s/token",
+    "public_csv": "%s/spreadsheets/d",
+    "auth": "%s/auth",
+    "revoke": "%s/revoke"
+  }
+
+(login を試す場合は credentials_file の代わりに -write-oauth-client で作成したファイルを oauth_client_file に、
+ 公開シートモードを試す場合は credentials_file を外します)
+
+`, *tsvFile, *sheetName, base, *sheetName, credPath, base, base, base, base, base)
+
+	srv := sheets.NewFakeServer(*tsvFile, *sheetName)
+	srv.SetFailRate(*failRate)
+	srv.SetLog(os.Stderr)
+	if err := http.Serve(ln, srv); err != nil {
+		exitError(err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"atena_printer/internal/importer"
+	"atena_printer/internal/model"
+)
+
+func cmdImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	from := fs.String("from", "", "取り込み元の形式 ("+strings.Join(importer.FormatNames(), " / ")+"。空の場合は自動判定)")
+	output := fs.String("output", "", "書き出す TSV ファイルのパス (空の場合はプレビューのみ)")
+	preview := fs.Int("preview", 3, "プレビューに表示する件数")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		exitError(fmt.Errorf("取り込む CSV ファイルを1つ指定してください"))
+	}
+
+	res, err := importer.ReadFile(fs.Arg(0), *from)
+	if err != nil {
+		exitError(err)
+	}
+
+	pairs, unused := res.Mapping.Preview()
+	fmt.Printf("形式: %s (%s)\n", res.Mapping.Format.Label, res.Encoding)
+	fmt.Println("--- 列の対応 ---")
+	for _, p := range pairs {
+		fmt.Printf("  %s ← %s\n", p[0], p[1])
+	}
+	if len(unused) > 0 {
+		fmt.Printf("  (取り込まない列: %s)\n", strings.Join(unused, ", "))
+	}
+
+	fmt.Printf("--- 先頭 %d件 (全 %d件", min(*preview, len(res.Addresses)), len(res.Addresses))
+	if res.Skipped > 0 {
+		fmt.Printf("、姓が空のため %d件を除外", res.Skipped)
+	}
+	fmt.Println(") ---")
+	for i, addr := range res.Addresses {
+		if i >= *preview {
+			break
+		}
+		fmt.Printf("  %s %s%s", addr.FamilyName, addr.GivenName, addr.Honorific)
+		if len(addr.JointNames) > 0 {
+			fmt.Printf(" (連名: %s)", strings.Join(addr.JointNames, "、"))
+		}
+		if addr.Reading != "" {
+			fmt.Printf(" [%s]", addr.Reading)
+		}
+		fmt.Printf("  〒%s %s %s", formatPostalCode(addr.PostalCode), addr.Address1, addr.Address2)
+		for _, year := range sortedYears(res.Histories[i]) {
+			st := res.Histories[i][year]
+			if st.Sent || st.Received || st.Mourning {
+				fmt.Printf("  %d:%s", year, statusLabel(st))
+			}
+		}
+		fmt.Println()
+	}
+
+	if *output == "" {
+		fmt.Println("\n-output を指定すると TSV ファイルに書き出します。")
+		return
+	}
+
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		exitError(fmt.Errorf("出力ファイルの作成に失敗 (既存のファイルは上書きしません): %w", err))
+	}
+	if err := res.WriteTSV(f); err != nil {
+		f.Close()
+		exitError(fmt.Errorf("TSV の書き出しに失敗: %w", err))
+	}
+	if err := f.Close(); err != nil {
+		exitError(fmt.Errorf("TSV の書き出しに失敗: %w", err))
+	}
+	fmt.Printf("\n%d件を %s に書き出しました。config.json の tsv_file に指定して使えます。\n", len(res.Addresses), *output)
+}
+
+func sortedYears(h model.History) []int {
+	var years []int
+	for y := range h {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	return years
+}
+
+func statusLabel(st model.YearStatus) string {
+	var marks []string
+	if st.Sent {
+		marks = append(marks, "送")
+	}
+	if st.Received {
+		marks = append(marks, "受")
+	}
+	if st.Mourning {
+		marks = append(marks, "喪中")
+	}
+	return strings.Join(marks, "")
+}
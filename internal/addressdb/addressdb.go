@@ -0,0 +1,538 @@
+// Package addressdb はローカルの SQLite ファイルに住所録を保存する。
+//
+// テーブル:
+//
+//	contacts     宛先 (uid は model.Address.ID)
+//	addresses    郵便番号・住所 (宛先ごとに1件)
+//	joint_names  連名 (position 順)
+//	tags         タグ
+//	year_status  年ごとの送・受・喪中
+package addressdb
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"atena_printer/internal/model"
+
+	_ "modernc.org/sqlite"
+)
+
+// 年ステータスの種類 (year_status の列名)。
+const (
+	KindSent     = "sent"
+	KindReceived = "received"
+	KindMourning = "mourning"
+)
+
+// 住所の項目 (addresses の列名)。
+const (
+	FieldPostalCode = "postal_code"
+	FieldAddress1   = "address1"
+	FieldAddress2   = "address2"
+)
+
+// ErrNotFound は指定した ID の宛先が無いことを表す。
+var ErrNotFound = errors.New("宛先が見つかりません")
+
+const schema = `
+CREATE TABLE IF NOT EXISTS contacts (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	uid         TEXT NOT NULL UNIQUE,
+	family_name TEXT NOT NULL,
+	given_name  TEXT NOT NULL DEFAULT '',
+	reading     TEXT NOT NULL DEFAULT '',
+	honorific   TEXT NOT NULL DEFAULT '',
+	created_at  TEXT NOT NULL DEFAULT (datetime('now', 'localtime')),
+	updated_at  TEXT NOT NULL DEFAULT (datetime('now', 'localtime'))
+);
+CREATE TABLE IF NOT EXISTS addresses (
+	contact_id  INTEGER PRIMARY KEY REFERENCES contacts(id) ON DELETE CASCADE,
+	postal_code TEXT NOT NULL DEFAULT '',
+	address1    TEXT NOT NULL DEFAULT '',
+	address2    TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS joint_names (
+	contact_id INTEGER NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	name       TEXT NOT NULL,
+	PRIMARY KEY (contact_id, position)
+);
+CREATE TABLE IF NOT EXISTS tags (
+	contact_id INTEGER NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+	tag        TEXT NOT NULL,
+	PRIMARY KEY (contact_id, tag)
+);
+CREATE TABLE IF NOT EXISTS year_status (
+	contact_id INTEGER NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+	year       INTEGER NOT NULL,
+	sent       INTEGER NOT NULL DEFAULT 0,
+	received   INTEGER NOT NULL DEFAULT 0,
+	mourning   INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (contact_id, year)
+);
+`
+
+// DB は SQLite の住所録。
+type DB struct {
+	path string
+	db   *sql.DB
+}
+
+// Open は SQLite ファイルを開く。ファイルが無ければ作成し、テーブルを用意する。
+func Open(path string) (*DB, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("住所録データベースを開けません: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("住所録データベースの初期化に失敗: %w", err)
+	}
+	return &DB{path: path, db: db}, nil
+}
+
+// Close はデータベースを閉じる。
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Path はデータベースファイルのパスを返す。
+func (d *DB) Path() string {
+	return d.path
+}
+
+// Count は登録されている宛先の件数を返す。
+func (d *DB) Count() (int, error) {
+	var n int
+	if err := d.db.QueryRow(`SELECT count(*) FROM contacts`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("住所録の読み込みに失敗: %w", err)
+	}
+	return n, nil
+}
+
+// List はすべての宛先と年ごとのステータスを登録順に返す。
+// Row には contacts.id を入れる (行の並べ替えで変わらない)。
+func (d *DB) List() ([]model.Address, map[int]model.History, error) {
+	rows, err := d.db.Query(`
+		SELECT c.id, c.uid, c.family_name, c.given_name, c.reading, c.honorific,
+		       coalesce(a.postal_code, ''), coalesce(a.address1, ''), coalesce(a.address2, '')
+		FROM contacts c LEFT JOIN addresses a ON a.contact_id = c.id
+		ORDER BY c.id`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("住所録の読み込みに失敗: %w", err)
+	}
+	defer rows.Close()
+
+	var addresses []model.Address
+	index := make(map[int]int) // contacts.id → addresses の位置
+	for rows.Next() {
+		var a model.Address
+		if err := rows.Scan(&a.Row, &a.ID, &a.FamilyName, &a.GivenName, &a.Reading, &a.Honorific,
+			&a.PostalCode, &a.Address1, &a.Address2); err != nil {
+			return nil, nil, fmt.Errorf("住所録の読み込みに失敗: %w", err)
+		}
+		if a.Honorific == "" {
+			a.Honorific = "様"
+		}
+		index[a.Row] = len(addresses)
+		addresses = append(addresses, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("住所録の読み込みに失敗: %w", err)
+	}
+
+	if err := d.eachRow(`SELECT contact_id, name FROM joint_names ORDER BY contact_id, position`, func(id int, v string) {
+		if i, ok := index[id]; ok {
+			addresses[i].JointNames = append(addresses[i].JointNames, v)
+		}
+	}); err != nil {
+		return nil, nil, err
+	}
+	if err := d.eachRow(`SELECT contact_id, tag FROM tags ORDER BY contact_id, rowid`, func(id int, v string) {
+		if i, ok := index[id]; ok {
+			addresses[i].Tags = append(addresses[i].Tags, v)
+		}
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	histories := make(map[int]model.History, len(addresses))
+	for _, a := range addresses {
+		histories[a.Row] = make(model.History)
+	}
+	st, err := d.db.Query(`SELECT contact_id, year, sent, received, mourning FROM year_status`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("年ステータスの読み込みに失敗: %w", err)
+	}
+	defer st.Close()
+	for st.Next() {
+		var id, year int
+		var ys model.YearStatus
+		if err := st.Scan(&id, &year, &ys.Sent, &ys.Received, &ys.Mourning); err != nil {
+			return nil, nil, fmt.Errorf("年ステータスの読み込みに失敗: %w", err)
+		}
+		if h, ok := histories[id]; ok {
+			h[year] = ys
+		}
+	}
+	if err := st.Err(); err != nil {
+		return nil, nil, fmt.Errorf("年ステータスの読み込みに失敗: %w", err)
+	}
+	return addresses, histories, nil
+}
+
+func (d *DB) eachRow(query string, fn func(id int, v string)) error {
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("住所録の読み込みに失敗: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var v string
+		if err := rows.Scan(&id, &v); err != nil {
+			return fmt.Errorf("住所録の読み込みに失敗: %w", err)
+		}
+		fn(id, v)
+	}
+	return rows.Err()
+}
+
+// Get は ID (uid) の宛先と年ごとのステータスを返す。
+func (d *DB) Get(uid string) (model.Address, model.History, error) {
+	addresses, histories, err := d.List()
+	if err != nil {
+		return model.Address{}, nil, err
+	}
+	for _, a := range addresses {
+		if a.ID == uid {
+			return a, histories[a.Row], nil
+		}
+	}
+	return model.Address{}, nil, fmt.Errorf("ID %s: %w", uid, ErrNotFound)
+}
+
+// Add は宛先を追加し、割り当てた ID を返す。addr.ID が空の場合は新しい ID を割り当てる。
+func (d *DB) Add(addr model.Address, history model.History) (string, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("宛先の追加に失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	uid, err := add(tx, addr, history)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("宛先の追加に失敗: %w", err)
+	}
+	return uid, nil
+}
+
+// Import は宛先をまとめて追加し、追加した件数を返す。すべて追加するか、何も追加しない。
+func (d *DB) Import(addresses []model.Address, histories map[int]model.History) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("取り込みに失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, a := range addresses {
+		if _, err := add(tx, a, histories[a.Row]); err != nil {
+			return 0, fmt.Errorf("%s %s (%d行目): %w", a.FamilyName, a.GivenName, a.Row, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("取り込みに失敗: %w", err)
+	}
+	return len(addresses), nil
+}
+
+func add(tx *sql.Tx, addr model.Address, history model.History) (string, error) {
+	if addr.FamilyName == "" {
+		return "", fmt.Errorf("姓が空です")
+	}
+	uid := addr.ID
+	if uid == "" {
+		var err error
+		if uid, err = newUID(tx); err != nil {
+			return "", err
+		}
+	}
+
+	res, err := tx.Exec(`INSERT INTO contacts (uid, family_name, given_name, reading, honorific) VALUES (?, ?, ?, ?, ?)`,
+		uid, addr.FamilyName, addr.GivenName, addr.Reading, honorific(addr.Honorific))
+	if err != nil {
+		return "", fmt.Errorf("宛先の追加に失敗 (ID %s): %w", uid, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("宛先の追加に失敗: %w", err)
+	}
+	if err := writeDetails(tx, id, addr); err != nil {
+		return "", err
+	}
+	for year, st := range history {
+		if !st.Sent && !st.Received && !st.Mourning {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO year_status (contact_id, year, sent, received, mourning) VALUES (?, ?, ?, ?, ?)`,
+			id, year, st.Sent, st.Received, st.Mourning); err != nil {
+			return "", fmt.Errorf("年ステータスの追加に失敗: %w", err)
+		}
+	}
+	return uid, nil
+}
+
+// Update は ID (addr.ID) の宛先の氏名・住所・連名・タグを addr の内容に置き換える。
+func (d *DB) Update(addr model.Address) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("宛先の更新に失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err := contactID(tx, addr.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE contacts SET family_name = ?, given_name = ?, reading = ?, honorific = ?,
+		updated_at = datetime('now', 'localtime') WHERE id = ?`,
+		addr.FamilyName, addr.GivenName, addr.Reading, honorific(addr.Honorific), id); err != nil {
+		return fmt.Errorf("宛先の更新に失敗: %w", err)
+	}
+	for _, table := range []string{"addresses", "joint_names", "tags"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE contact_id = ?`, id); err != nil {
+			return fmt.Errorf("宛先の更新に失敗: %w", err)
+		}
+	}
+	if err := writeDetails(tx, id, addr); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("宛先の更新に失敗: %w", err)
+	}
+	return nil
+}
+
+// Remove は ID の宛先を削除する (住所・連名・タグ・年ステータスも削除される)。
+func (d *DB) Remove(uid string) error {
+	res, err := d.db.Exec(`DELETE FROM contacts WHERE uid = ?`, uid)
+	if err != nil {
+		return fmt.Errorf("宛先の削除に失敗: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("ID %s: %w", uid, ErrNotFound)
+	}
+	return nil
+}
+
+// Status は ID の宛先の year 年のステータス (kind) を返す。
+func (d *DB) Status(uid string, year int, kind string) (bool, error) {
+	if err := checkKind(kind); err != nil {
+		return false, err
+	}
+	var v bool
+	err := d.db.QueryRow(`SELECT s.`+kind+` FROM year_status s JOIN contacts c ON c.id = s.contact_id
+		WHERE c.uid = ? AND s.year = ?`, uid, year).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := contactID(d.db, uid); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("年ステータスの読み込みに失敗: %w", err)
+	}
+	return v, nil
+}
+
+// StatusChange は1件の年ステータスの書き換え。
+type StatusChange struct {
+	UID   string
+	Year  int
+	Kind  string
+	Value bool
+}
+
+// SetStatuses は年ステータスをまとめて書き換える。すべて書き換えるか、何も書き換えない。
+func (d *DB) SetStatuses(changes []StatusChange) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("年ステータスの書き込みに失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, ch := range changes {
+		if err := checkKind(ch.Kind); err != nil {
+			return err
+		}
+		id, err := contactID(tx, ch.UID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO year_status (contact_id, year, `+ch.Kind+`) VALUES (?, ?, ?)
+			ON CONFLICT (contact_id, year) DO UPDATE SET `+ch.Kind+` = excluded.`+ch.Kind,
+			id, ch.Year, ch.Value); err != nil {
+			return fmt.Errorf("年ステータスの書き込みに失敗: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("年ステータスの書き込みに失敗: %w", err)
+	}
+	return nil
+}
+
+// AddressField は ID の宛先の住所の項目 (field) の値を返す。
+func (d *DB) AddressField(uid, field string) (string, error) {
+	if err := checkField(field); err != nil {
+		return "", err
+	}
+	id, err := contactID(d.db, uid)
+	if err != nil {
+		return "", err
+	}
+	var v string
+	err = d.db.QueryRow(`SELECT `+field+` FROM addresses WHERE contact_id = ?`, id).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("住所の読み込みに失敗: %w", err)
+	}
+	return v, nil
+}
+
+// AddressChange は1件の住所の項目の書き換え。
+type AddressChange struct {
+	UID   string
+	Field string
+	Value string
+}
+
+// SetAddressFields は住所の項目をまとめて書き換える。すべて書き換えるか、何も書き換えない。
+func (d *DB) SetAddressFields(changes []AddressChange) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("住所の書き込みに失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, ch := range changes {
+		if err := checkField(ch.Field); err != nil {
+			return err
+		}
+		id, err := contactID(tx, ch.UID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO addresses (contact_id, `+ch.Field+`) VALUES (?, ?)
+			ON CONFLICT (contact_id) DO UPDATE SET `+ch.Field+` = excluded.`+ch.Field,
+			id, ch.Value); err != nil {
+			return fmt.Errorf("住所の書き込みに失敗: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE contacts SET updated_at = datetime('now', 'localtime') WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("住所の書き込みに失敗: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("住所の書き込みに失敗: %w", err)
+	}
+	return nil
+}
+
+// Years は年ステータスが記録されている年を昇順で返す。
+func Years(h model.History) []int {
+	var years []int
+	for y := range h {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	return years
+}
+
+// --- helpers ---
+
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func contactID(q queryer, uid string) (int64, error) {
+	var id int64
+	err := q.QueryRow(`SELECT id FROM contacts WHERE uid = ?`, uid).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("ID %s: %w", uid, ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("住所録の読み込みに失敗: %w", err)
+	}
+	return id, nil
+}
+
+func writeDetails(tx *sql.Tx, id int64, addr model.Address) error {
+	if _, err := tx.Exec(`INSERT INTO addresses (contact_id, postal_code, address1, address2) VALUES (?, ?, ?, ?)`,
+		id, addr.PostalCode, addr.Address1, addr.Address2); err != nil {
+		return fmt.Errorf("住所の書き込みに失敗: %w", err)
+	}
+	for i, name := range addr.JointNames {
+		if _, err := tx.Exec(`INSERT INTO joint_names (contact_id, position, name) VALUES (?, ?, ?)`, id, i+1, name); err != nil {
+			return fmt.Errorf("連名の書き込みに失敗: %w", err)
+		}
+	}
+	for _, tag := range addr.Tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (contact_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return fmt.Errorf("タグの書き込みに失敗: %w", err)
+		}
+	}
+	return nil
+}
+
+// newUID は未使用の ID (16進8桁。シートの assign-ids と同じ形式) を返す。
+func newUID(q queryer) (string, error) {
+	for {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("ID の生成に失敗: %w", err)
+		}
+		uid := hex.EncodeToString(b)
+		var n int
+		if err := q.QueryRow(`SELECT count(*) FROM contacts WHERE uid = ?`, uid).Scan(&n); err != nil {
+			return "", fmt.Errorf("ID の生成に失敗: %w", err)
+		}
+		if n == 0 {
+			return uid, nil
+		}
+	}
+}
+
+// honorific は「様」を空として保存する (読み込み時に「様」を補う)。
+func honorific(h string) string {
+	if h == "様" {
+		return ""
+	}
+	return h
+}
+
+func checkKind(kind string) error {
+	switch kind {
+	case KindSent, KindReceived, KindMourning:
+		return nil
+	}
+	return fmt.Errorf("不明な年ステータスの種類: %s", kind)
+}
+
+func checkField(field string) error {
+	switch field {
+	case FieldPostalCode, FieldAddress1, FieldAddress2:
+		return nil
+	}
+	return fmt.Errorf("不明な住所の項目: %s", field)
+}
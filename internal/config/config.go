@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -15,16 +16,165 @@ type Sender struct {
 	Address2   string `json:"address2"`
 }
 
+// BackTemplate は裏面 (文面) の内容。{year} は対象年、{prev_year} はその前年に置き換えられる。
+type BackTemplate struct {
+	Title string   `json:"title"`
+	Lines []string `json:"lines"`
+	Date  string   `json:"date"`
+}
+
+// defaultBackTemplates は generate -mode ごとの裏面の既定値。
+var defaultBackTemplates = map[string]BackTemplate{
+	"reply": {
+		Title: "寒中お見舞い申し上げます",
+		Lines: []string{
+			"ご丁寧な年賀状をいただき",
+			"ありがとうございました",
+			"ご挨拶が遅れましたことを",
+			"お詫び申し上げます",
+			"寒さ厳しき折から",
+			"くれぐれもご自愛ください",
+		},
+		Date: "{year}年 一月",
+	},
+	"mourning-notice": {
+		Title: "喪中につき年末年始のご挨拶を",
+		Lines: []string{
+			"謹んでご遠慮申し上げます",
+			"本年中に賜りましたご厚情に",
+			"深く感謝いたしますとともに",
+			"明年も変わらぬご交誼のほど",
+			"よろしくお願い申し上げます",
+		},
+		Date: "{prev_year}年 十一月",
+	},
+}
+
+// Columns はシートの列と項目の対応。
+// 各項目にはヘッダ名か列記号 (A, B, ..., AA) を指定する。同じ名前のヘッダがあればヘッダを優先する。
+// 空の項目は DefaultColumns のヘッダ名で探す。
+// Sent / Received / Mourning は年ステータス列のヘッダで、{year} が年 (4桁) に置き換わる。
+type Columns struct {
+	ID         string `json:"id"`
+	FamilyName string `json:"family_name"`
+	GivenName  string `json:"given_name"`
+	FullName   string `json:"full_name"` // 姓名が1列の場合 (空白で姓と名に分ける)
+	Reading    string `json:"reading"`
+	JointNames string `json:"joint_names"`
+	Honorific  string `json:"honorific"`
+	PostalCode string `json:"postal_code"`
+	Address1   string `json:"address1"`
+	Address2   string `json:"address2"`
+	Tags       string `json:"tags"`
+
+	Sent     string `json:"sent"`
+	Received string `json:"received"`
+	Mourning string `json:"mourning"`
+}
+
+// DefaultColumns は README のヘッダ行に対応する既定の列名。
+var DefaultColumns = Columns{
+	ID:         "ID",
+	FamilyName: "姓",
+	GivenName:  "名",
+	FullName:   "氏名",
+	Reading:    "よみ",
+	JointNames: "連名",
+	Honorific:  "敬称",
+	PostalCode: "郵便番号",
+	Address1:   "住所1",
+	Address2:   "住所2",
+	Tags:       "タグ",
+	Sent:       "{year}送",
+	Received:   "{year}受",
+	Mourning:   "{year}喪中",
+}
+
+// WithDefaults は空の項目を既定の列名で埋めた Columns を返す。
+func (c Columns) WithDefaults() Columns {
+	fill := func(v *string, def string) {
+		if *v == "" {
+			*v = def
+		}
+	}
+	d := DefaultColumns
+	fill(&c.ID, d.ID)
+	fill(&c.FamilyName, d.FamilyName)
+	fill(&c.GivenName, d.GivenName)
+	fill(&c.FullName, d.FullName)
+	fill(&c.Reading, d.Reading)
+	fill(&c.JointNames, d.JointNames)
+	fill(&c.Honorific, d.Honorific)
+	fill(&c.PostalCode, d.PostalCode)
+	fill(&c.Address1, d.Address1)
+	fill(&c.Address2, d.Address2)
+	fill(&c.Tags, d.Tags)
+	fill(&c.Sent, d.Sent)
+	fill(&c.Received, d.Received)
+	fill(&c.Mourning, d.Mourning)
+	return c
+}
+
+// StatusValues は年ステータス列 (YYYY送 など) の値の解釈。英字の大文字小文字は区別しない。
+// 空のセルは常に「無し」。どちらにも無い値は「有り」として扱い、警告を表示する。
+type StatusValues struct {
+	True  []string `json:"true"`
+	False []string `json:"false"`
+}
+
+// DefaultStatusValues はチェックボックス (TRUE / FALSE) と手入力でよく使う記号。
+var DefaultStatusValues = StatusValues{
+	True:  []string{"○", "◯", "〇", "TRUE", "✓", "✔", "☑", "レ", "済", "1", "有"},
+	False: []string{"×", "✕", "✗", "FALSE", "-", "ー", "−", "☐", "未", "0", "無"},
+}
+
+// API は Google API 呼び出しのタイムアウトと再試行の設定。
+type API struct {
+	TimeoutSeconds    int `json:"timeout_seconds"`     // 1回のリクエストのタイムアウト
+	MaxRetries        int `json:"max_retries"`         // 429 / 5xx・通信エラー時の再試行回数
+	MaxBackoffSeconds int `json:"max_backoff_seconds"` // 再試行までの待ち時間の上限
+}
+
+// Endpoints は Google の各 API の URL。fake-sheets などのエミュレータを使う場合に変更する。空の項目は Google の URL。
+type Endpoints struct {
+	SheetsAPI string `json:"sheets_api"` // 既定: https://sheets.googleapis.com/v4/spreadsheets
+	Token     string `json:"token"`      // 既定: 認証ファイルの token_uri
+	PublicCSV string `json:"public_csv"` // 既定: https://docs.google.com/spreadsheets/d
+	Auth      string `json:"auth"`       // login で開く認可画面。既定: OAuth クライアントファイルの auth_uri
+	Revoke    string `json:"revoke"`     // logout でのトークン無効化。既定: https://oauth2.googleapis.com/revoke
+}
+
+// DefaultSheetName は sheet_name を指定しなかった場合のシート名。
+const DefaultSheetName = "住所録"
+
 type Config struct {
+	Source          string `json:"source"` // データソース名 (空なら tsv_file / credentials_file から判定)
 	SpreadsheetID   string `json:"spreadsheet_id"`
 	SheetName       string `json:"sheet_name"`
 	CredentialsFile string `json:"credentials_file"`
+	OAuthClientFile string `json:"oauth_client_file"` // login で使う OAuth クライアント (デスクトップ アプリ)
 	TSVFile         string `json:"tsv_file"`
+	AddressFile     string `json:"address_file"` // CSV / TSV / XLSX (読み取り専用)
+	SQLiteFile      string `json:"sqlite_file"`
 	FontFile        string `json:"font_file"`
 	PostalFontFile  string `json:"postal_font_file"`
 	OutputFile      string `json:"output_file"`
+	JournalFile     string `json:"journal_file"`
 	Year            int    `json:"year"`
 	Sender          Sender `json:"sender"`
+
+	// credentials_file・oauth_client_file 以外の Google の認証方法。
+	// ApplicationDefault は credentials_file が空なら GOOGLE_APPLICATION_CREDENTIALS か gcloud の認証情報を使う。
+	ApplicationDefault bool   `json:"application_default_credentials"`
+	ImpersonateUser    string `json:"impersonate_user"` // ドメイン全体の委任で代理するユーザー (サービスアカウントのみ)
+	TokenCommand       string `json:"token_command"`    // アクセストークンを出力するコマンド
+
+	Columns       Columns                 `json:"columns"`
+	StatusValues  StatusValues            `json:"status_values"`
+	API           API                     `json:"api"`
+	Endpoints     Endpoints               `json:"endpoints"`
+	BackTemplates map[string]BackTemplate `json:"back_templates"`
+	PostalFiles   []string                `json:"postal_files"` // 郵便番号データ (KEN_ALL.CSV・JIGYOSYO.CSV)
 }
 
 func Load(path string) (*Config, error) {
@@ -34,17 +184,46 @@ func Load(path string) (*Config, error) {
 	}
 
 	cfg := &Config{
-		SheetName:  "住所録",
-		OutputFile: "nenga.pdf",
-		Year:       time.Now().Year(),
+		SheetName:   DefaultSheetName,
+		OutputFile:  "nenga.pdf",
+		JournalFile: "atena_printer.journal.jsonl",
+		Year:        time.Now().Year(),
+		API:         API{TimeoutSeconds: 30, MaxRetries: 4, MaxBackoffSeconds: 30},
 	}
 
 	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("設定ファイルの形式が不正です: %w", err)
 	}
 
-	if cfg.SpreadsheetID == "" && cfg.TSVFile == "" {
-		return nil, fmt.Errorf("spreadsheet_id または tsv_file のどちらかを設定してください")
+	cols := cfg.Columns.WithDefaults()
+	for name, pattern := range map[string]string{"sent": cols.Sent, "received": cols.Received, "mourning": cols.Mourning} {
+		if strings.Count(pattern, "{year}") != 1 {
+			return nil, fmt.Errorf("columns.%s には {year} を1つ含めてください: %q", name, pattern)
+		}
+	}
+
+	if cfg.API.TimeoutSeconds < 0 || cfg.API.MaxRetries < 0 || cfg.API.MaxBackoffSeconds < 0 {
+		return nil, fmt.Errorf("api の timeout_seconds・max_retries・max_backoff_seconds には0以上を指定してください")
+	}
+
+	if len(cfg.StatusValues.True) == 0 {
+		cfg.StatusValues.True = DefaultStatusValues.True
+	}
+	if len(cfg.StatusValues.False) == 0 {
+		cfg.StatusValues.False = DefaultStatusValues.False
+	}
+
+	if cfg.BackTemplates == nil {
+		cfg.BackTemplates = make(map[string]BackTemplate)
+	}
+	for mode, tmpl := range defaultBackTemplates {
+		if _, ok := cfg.BackTemplates[mode]; !ok {
+			cfg.BackTemplates[mode] = tmpl
+		}
+	}
+
+	if cfg.SpreadsheetID == "" && cfg.TSVFile == "" && cfg.AddressFile == "" && cfg.SQLiteFile == "" {
+		return nil, fmt.Errorf("spreadsheet_id・tsv_file・address_file・sqlite_file のいずれかを設定してください")
 	}
 	if cfg.FontFile == "" {
 		return nil, fmt.Errorf("font_file が設定されていません")
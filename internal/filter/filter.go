@@ -0,0 +1,349 @@
+// Package filter は generate / list / mark-sent の -where で使う絞り込み式を扱う。
+//
+// 式の例:
+//
+//	tag:仕事 && !sent && received(2025)
+//	(pref:東京都 || pref:神奈川) && honorific:先生
+//
+// 使える項目:
+//
+//	sent, received, mourning        対象年のステータス
+//	sent(2025) など                  指定年のステータス
+//	tag:値                           タグ列に値を含む
+//	pref:値                          住所1の都道府県 (「都」「府」「県」は省略可)
+//	honorific:値                     敬称
+//
+// 演算子は ! (否定)、&& (かつ)、|| (または) と括弧。
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"atena_printer/internal/model"
+)
+
+// Env は式を評価する対象の1件。
+type Env struct {
+	Address model.Address
+	History model.History
+	Year    int // sent などで年を省略したときの年
+}
+
+// Expr は解析済みの絞り込み式。
+type Expr interface {
+	Eval(env Env) bool
+}
+
+// Parse は絞り込み式を解析する。空文字列はすべてに一致する式になる。
+func Parse(src string) (Expr, error) {
+	p := &parser{src: []rune(src)}
+	p.next()
+	if p.tok.kind == tokEOF {
+		return always{}, nil
+	}
+	e, err := p.parseOr()
+	if p.lexErr != nil {
+		return nil, p.lexErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("余分な '%s' があります", p.tok.text)
+	}
+	return e, nil
+}
+
+// --- 式 ---
+
+type always struct{}
+
+func (always) Eval(Env) bool { return true }
+
+type notExpr struct{ x Expr }
+
+func (e notExpr) Eval(env Env) bool { return !e.x.Eval(env) }
+
+type andExpr struct{ l, r Expr }
+
+func (e andExpr) Eval(env Env) bool { return e.l.Eval(env) && e.r.Eval(env) }
+
+type orExpr struct{ l, r Expr }
+
+func (e orExpr) Eval(env Env) bool { return e.l.Eval(env) || e.r.Eval(env) }
+
+type statusExpr struct {
+	field string // sent / received / mourning
+	year  int    // 0 なら Env.Year
+}
+
+func (e statusExpr) Eval(env Env) bool {
+	year := e.year
+	if year == 0 {
+		year = env.Year
+	}
+	st := env.History[year]
+	switch e.field {
+	case "sent":
+		return st.Sent
+	case "received":
+		return st.Received
+	case "mourning":
+		return st.Mourning
+	}
+	return false
+}
+
+type fieldExpr struct {
+	field string // tag / pref / honorific
+	value string
+}
+
+func (e fieldExpr) Eval(env Env) bool {
+	addr := env.Address
+	switch e.field {
+	case "tag":
+		for _, t := range addr.Tags {
+			if t == e.value {
+				return true
+			}
+		}
+		return false
+	case "pref":
+		pref := model.Prefecture(addr.Address1)
+		return pref != "" && (pref == e.value || trimPrefSuffix(pref) == trimPrefSuffix(e.value))
+	case "honorific":
+		return addr.Honorific == e.value
+	}
+	return false
+}
+
+// trimPrefSuffix は都道府県名の末尾の「都」「府」「県」を1文字だけ除く (北海道はそのまま)。
+func trimPrefSuffix(s string) string {
+	switch s {
+	case "北海道":
+		return s
+	case "東京都":
+		return "東京"
+	}
+	for _, suffix := range []string{"府", "県"} {
+		if rest, ok := strings.CutSuffix(s, suffix); ok {
+			return rest
+		}
+	}
+	return s
+}
+
+// --- 字句解析 ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokNot
+	tokAnd
+	tokOr
+	tokLParen
+	tokRParen
+	tokColon
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src []rune
+	pos int
+	tok token
+	// lexErr は字句解析の誤り (閉じていない引用符など)。構文の誤りより優先して返す。
+	lexErr error
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return p.errorAt(p.tok.pos, format, args...)
+}
+
+func (p *parser) errorAt(pos int, format string, args ...any) error {
+	return fmt.Errorf("-where の %d 文字目: %s", pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	two := ""
+	if p.pos+1 < len(p.src) {
+		two = string(p.src[p.pos : p.pos+2])
+	}
+	switch {
+	case two == "&&":
+		p.pos += 2
+		p.tok = token{kind: tokAnd, text: two, pos: start}
+		return
+	case two == "||":
+		p.pos += 2
+		p.tok = token{kind: tokOr, text: two, pos: start}
+		return
+	}
+
+	switch r := p.src[p.pos]; r {
+	case '!':
+		p.pos++
+		p.tok = token{kind: tokNot, text: "!", pos: start}
+	case '(':
+		p.pos++
+		p.tok = token{kind: tokLParen, text: "(", pos: start}
+	case ')':
+		p.pos++
+		p.tok = token{kind: tokRParen, text: ")", pos: start}
+	case ':':
+		p.pos++
+		p.tok = token{kind: tokColon, text: ":", pos: start}
+	case '"', '\'':
+		p.pos++
+		var b strings.Builder
+		for p.pos < len(p.src) && p.src[p.pos] != r {
+			b.WriteRune(p.src[p.pos])
+			p.pos++
+		}
+		if p.pos >= len(p.src) && p.lexErr == nil {
+			p.lexErr = p.errorAt(start, "引用符 %c が閉じていません", r)
+		}
+		p.pos++ // 閉じ引用符
+		p.tok = token{kind: tokWord, text: b.String(), pos: start}
+	default:
+		for p.pos < len(p.src) && !isDelimiter(p.src, p.pos) {
+			p.pos++
+		}
+		p.tok = token{kind: tokWord, text: string(p.src[start:p.pos]), pos: start}
+	}
+}
+
+func isDelimiter(src []rune, i int) bool {
+	switch r := src[i]; {
+	case unicode.IsSpace(r), r == '!', r == '(', r == ')', r == ':':
+		return true
+	case (r == '&' || r == '|') && i+1 < len(src) && src[i+1] == r:
+		return true
+	}
+	return false
+}
+
+// --- 構文解析 ---
+
+func (p *parser) parseOr() (Expr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orExpr{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokAnd {
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = andExpr{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.tok.kind == tokNot {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	switch p.tok.kind {
+	case tokLParen:
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("')' がありません")
+		}
+		p.next()
+		return e, nil
+	case tokWord:
+		return p.parseTerm()
+	case tokEOF:
+		return nil, p.errorf("式が途中で終わっています")
+	}
+	return nil, p.errorf("'%s' は使えません", p.tok.text)
+}
+
+func (p *parser) parseTerm() (Expr, error) {
+	name, pos := p.tok.text, p.tok.pos
+	p.next()
+
+	switch name {
+	case "sent", "received", "mourning":
+		e := statusExpr{field: name}
+		if p.tok.kind == tokLParen {
+			p.next()
+			if p.tok.kind != tokWord {
+				return nil, p.errorf("%s( の後に年を指定してください", name)
+			}
+			year, err := strconv.Atoi(p.tok.text)
+			if err != nil {
+				return nil, p.errorf("年が不正です: %s", p.tok.text)
+			}
+			e.year = year
+			p.next()
+			if p.tok.kind != tokRParen {
+				return nil, p.errorf("')' がありません")
+			}
+			p.next()
+		}
+		return e, nil
+	case "tag", "pref", "honorific":
+		if p.tok.kind != tokColon {
+			return nil, p.errorf("%s の後に ':値' を指定してください", name)
+		}
+		p.next()
+		if p.tok.kind != tokWord {
+			return nil, p.errorf("%s: の後に値を指定してください", name)
+		}
+		value := p.tok.text
+		p.next()
+		return fieldExpr{field: name, value: value}, nil
+	}
+	return nil, p.errorAt(pos, "不明な項目 '%s' (sent / received / mourning / tag / pref / honorific が使えます)", name)
+}
@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"atena_printer/internal/model"
+)
+
+func TestParseEval(t *testing.T) {
+	env := Env{
+		Address: model.Address{
+			FamilyName: "佐藤",
+			Address1:   "京都府京都市中京区",
+			Honorific:  "先生",
+			Tags:       []string{"仕事", "大学"},
+		},
+		History: model.History{
+			2025: {Sent: true, Received: true},
+			2026: {Received: true},
+		},
+		Year: 2026,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"", true},
+		{"sent", false},
+		{"received", true},
+		{"!sent && received", true},
+		{"sent(2025)", true},
+		{"mourning(2025)", false},
+		{"tag:仕事", true},
+		{"tag:家族", false},
+		{`tag:"大学"`, true},
+		{"pref:京都府", true},
+		{"pref:京都", true},
+		{"pref:京", false},
+		{"pref:東京", false},
+		{"honorific:先生", true},
+		{"tag:家族 || honorific:先生", true},
+		{"(tag:家族 || tag:仕事) && !received(2025)", false},
+		{"!!sent", false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := e.Eval(env); got != tt.want {
+			t.Errorf("Parse(%q).Eval = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParsePref(t *testing.T) {
+	tests := []struct {
+		address, value string
+		want           bool
+	}{
+		{"東京都千代田区", "東京", true},
+		{"東京都千代田区", "東京都", true},
+		{"京都府京都市", "京都", true},
+		{"京都府京都市", "東京", false},
+		{"北海道札幌市", "北海道", true},
+		{"北海道札幌市", "北海", false},
+		{"神奈川県横浜市", "神奈川", true},
+		{"横浜市", "神奈川", false},
+	}
+	for _, tt := range tests {
+		e, err := Parse("pref:" + tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := Env{Address: model.Address{Address1: tt.address}}
+		if got := e.Eval(env); got != tt.want {
+			t.Errorf("pref:%s on %s = %v, want %v", tt.value, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // エラーメッセージに含まれる文字列
+	}{
+		{"foo", "1 文字目: 不明な項目 'foo'"},
+		{"sent && foo", "9 文字目: 不明な項目 'foo'"},
+		{"tag:\"仕事", "5 文字目: 引用符 \" が閉じていません"},
+		{"tag:'仕事 && sent", "引用符 ' が閉じていません"},
+		{"(sent", "')' がありません"},
+		{"sent &&", "式が途中で終わっています"},
+		{"sent sent", "余分な 'sent'"},
+		{"tag", "tag の後に ':値'"},
+		{"sent(x)", "年が不正です"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if err == nil {
+			t.Errorf("Parse(%q): エラーになりません", tt.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q を含む", tt.expr, err, tt.want)
+		}
+	}
+}
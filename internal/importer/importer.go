@@ -0,0 +1,475 @@
+// Package importer は市販の年賀状ソフト (筆まめ・筆ぐるめ・宛名職人) が書き出した CSV を
+// atena_printer の TSV 形式に変換する。
+//
+// ソフトやバージョンによって列名が少しずつ違うため、項目ごとに列名の候補を持ち、
+// ヘッダ行に含まれる特徴的な列名から形式を判定する。
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"atena_printer/internal/model"
+	"atena_printer/internal/postal"
+	"atena_printer/internal/textenc"
+)
+
+// Format は取り込み元ソフトの CSV 形式。
+type Format struct {
+	Name  string // -from で指定する名前
+	Label string // 表示名
+
+	// 判定に使う特徴的な列名 (一致した数が多い形式を選ぶ)
+	Signature []string
+
+	// 項目ごとの列名の候補 (先に書いたものを優先)
+	Family, Given, FullName     []string
+	FamilyReading, GivenReading []string
+	FullReading                 []string
+	Honorific, PostalCode       []string
+	Address1                    []string
+	Address2                    []string // 複数一致した場合は空白でつなぐ
+	Tags                        []string
+	JointName                   string // 連名の列名 (%d に 1..5)
+}
+
+// Formats は対応している形式。
+var Formats = []Format{
+	{
+		Name:          "fudemame",
+		Label:         "筆まめ",
+		Signature:     []string{"自宅〒", "姓フリガナ", "名フリガナ", "自宅住所3", "分類"},
+		Family:        []string{"姓", "氏名(姓)"},
+		Given:         []string{"名", "氏名(名)"},
+		FullName:      []string{"氏名"},
+		FamilyReading: []string{"姓フリガナ", "フリガナ(姓)"},
+		GivenReading:  []string{"名フリガナ", "フリガナ(名)"},
+		FullReading:   []string{"氏名フリガナ", "フリガナ"},
+		Honorific:     []string{"敬称"},
+		PostalCode:    []string{"自宅〒", "自宅郵便番号", "郵便番号"},
+		Address1:      []string{"自宅住所1", "住所1"},
+		Address2:      []string{"自宅住所2", "自宅住所3", "住所2", "住所3"},
+		Tags:          []string{"分類", "グループ"},
+		JointName:     "連名%d",
+	},
+	{
+		Name:          "fudegurume",
+		Label:         "筆ぐるめ",
+		Signature:     []string{"氏名", "フリガナ", "自宅〒", "自宅住所1", "グループ"},
+		Family:        []string{"姓"},
+		Given:         []string{"名"},
+		FullName:      []string{"氏名"},
+		FamilyReading: []string{"姓フリガナ"},
+		GivenReading:  []string{"名フリガナ"},
+		FullReading:   []string{"フリガナ", "氏名フリガナ"},
+		Honorific:     []string{"敬称"},
+		PostalCode:    []string{"自宅〒", "自宅郵便番号", "郵便番号"},
+		Address1:      []string{"自宅住所1", "住所1"},
+		Address2:      []string{"自宅住所2", "自宅住所3", "住所2", "住所3"},
+		Tags:          []string{"グループ", "分類"},
+		JointName:     "連名%d",
+	},
+	{
+		Name:          "atenashokunin",
+		Label:         "宛名職人",
+		Signature:     []string{"姓ふりがな", "名ふりがな", "自宅郵便番号", "自宅住所1", "敬称"},
+		Family:        []string{"姓"},
+		Given:         []string{"名"},
+		FullName:      []string{"氏名"},
+		FamilyReading: []string{"姓ふりがな"},
+		GivenReading:  []string{"名ふりがな"},
+		FullReading:   []string{"ふりがな", "氏名ふりがな"},
+		Honorific:     []string{"敬称"},
+		PostalCode:    []string{"自宅郵便番号", "自宅〒", "郵便番号"},
+		Address1:      []string{"自宅住所1", "住所1"},
+		Address2:      []string{"自宅住所2", "自宅住所3", "住所2", "住所3"},
+		Tags:          []string{"分類", "グループ", "カテゴリ"},
+		JointName:     "連名%d",
+	},
+}
+
+// maxJointNames は連名列 (連名1..連名5) の数。
+const maxJointNames = 5
+
+// 送受信の履歴列。ソフトによって「2025年賀状送信」「2025送」「送信2025」などの形がある。
+var (
+	historyYearFirst = regexp.MustCompile(`^(\d{4})\s*年?\s*(?:年賀状?)?\s*(送信|受信|送|受|喪中)$`)
+	historyYearLast  = regexp.MustCompile(`^(?:年賀状?)?\s*(送信|受信|送|受|喪中)\s*(\d{4})\s*年?$`)
+)
+
+// 送受信の列で「無し」とみなす値。これ以外の空でない値は ○ に変換する。
+var falseValues = map[string]bool{
+	"×": true, "✕": true, "-": true, "0": true, "FALSE": true, "false": true, "なし": true, "無": true,
+}
+
+// Mapping は取り込み元の列と変換先の項目の対応。
+type Mapping struct {
+	Format Format
+	Header []string
+
+	fields  map[string][]int // 変換先の項目名 → 取り込み元の列番号
+	joint   []int
+	history map[string]int // 「2025送」などの変換先の列名 → 取り込み元の列番号
+	years   []int
+}
+
+// 変換先の項目名 (README のヘッダと同じ)。
+const (
+	fieldFamily    = "姓"
+	fieldGiven     = "名"
+	fieldFullName  = "氏名"
+	fieldReading   = "よみ"
+	fieldFamilyRd  = "よみ(姓)"
+	fieldGivenRd   = "よみ(名)"
+	fieldHonorific = "敬称"
+	fieldPostal    = "郵便番号"
+	fieldAddress1  = "住所1"
+	fieldAddress2  = "住所2"
+	fieldTags      = "タグ"
+)
+
+// Result は読み込んだ CSV とその変換結果。
+type Result struct {
+	Mapping   *Mapping
+	Encoding  textenc.Encoding
+	Addresses []model.Address
+	Histories []model.History // Addresses と同じ順
+	Skipped   int             // 姓が空で取り込まなかった行の数
+}
+
+// Detect はヘッダ行から形式を判定する。判定できない場合はエラー。
+func Detect(header []string) (Format, error) {
+	has := make(map[string]bool)
+	for _, h := range header {
+		has[strings.TrimSpace(h)] = true
+	}
+
+	best, bestScore := -1, 0
+	for i, f := range Formats {
+		score := 0
+		for _, s := range f.Signature {
+			if has[s] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 || bestScore < 2 {
+		return Format{}, fmt.Errorf("CSV の形式を判定できません (-from で %s のいずれかを指定してください)", strings.Join(FormatNames(), " / "))
+	}
+	return Formats[best], nil
+}
+
+// Lookup は名前から形式を返す。
+func Lookup(name string) (Format, bool) {
+	for _, f := range Formats {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// FormatNames は対応している形式の名前を返す。
+func FormatNames() []string {
+	var names []string
+	for _, f := range Formats {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// ReadFile は CSV を読み込んで変換する。from が空の場合は形式を自動判定する。
+func ReadFile(path, from string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("取り込み元ファイルを開けません: %w", err)
+	}
+	text, enc, err := textenc.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = textenc.DetectDelimiter(text)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV の読み込みに失敗: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("取り込み元ファイルが空です")
+	}
+
+	var format Format
+	if from == "" {
+		format, err = Detect(records[0])
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var ok bool
+		format, ok = Lookup(from)
+		if !ok {
+			return nil, fmt.Errorf("不明な -from: %s (%s のいずれかを指定してください)", from, strings.Join(FormatNames(), " / "))
+		}
+	}
+
+	m := NewMapping(format, records[0])
+	if len(m.fields[fieldFamily]) == 0 && len(m.fields[fieldFullName]) == 0 {
+		return nil, fmt.Errorf("%s の形式として読み込めません: 姓 (または氏名) の列がありません", format.Label)
+	}
+
+	res := &Result{Mapping: m, Encoding: enc}
+	for _, rec := range records[1:] {
+		if strings.TrimSpace(strings.Join(rec, "")) == "" {
+			continue
+		}
+		addr, hist, ok := m.Convert(rec)
+		if !ok {
+			res.Skipped++
+			continue
+		}
+		res.Addresses = append(res.Addresses, addr)
+		res.Histories = append(res.Histories, hist)
+	}
+	return res, nil
+}
+
+// NewMapping はヘッダ行から列の対応を作る。
+func NewMapping(format Format, header []string) *Mapping {
+	m := &Mapping{
+		Format:  format,
+		Header:  header,
+		fields:  make(map[string][]int),
+		history: make(map[string]int),
+	}
+	index := make(map[string]int)
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		if _, dup := index[h]; !dup {
+			index[h] = i
+		}
+	}
+
+	first := func(field string, names []string) {
+		for _, n := range names {
+			if i, ok := index[n]; ok {
+				m.fields[field] = []int{i}
+				return
+			}
+		}
+	}
+	first(fieldFamily, format.Family)
+	first(fieldGiven, format.Given)
+	if len(m.fields[fieldFamily]) == 0 {
+		first(fieldFullName, format.FullName)
+	}
+	first(fieldFamilyRd, format.FamilyReading)
+	first(fieldGivenRd, format.GivenReading)
+	if len(m.fields[fieldFamilyRd]) == 0 {
+		first(fieldReading, format.FullReading)
+	}
+	first(fieldHonorific, format.Honorific)
+	first(fieldPostal, format.PostalCode)
+	first(fieldAddress1, format.Address1)
+	first(fieldTags, format.Tags)
+	// 住所2 は建物名などが複数列に分かれていることがあるため、一致した列をすべて使う
+	for _, n := range format.Address2 {
+		if i, ok := index[n]; ok {
+			m.fields[fieldAddress2] = append(m.fields[fieldAddress2], i)
+		}
+	}
+
+	for n := 1; n <= maxJointNames; n++ {
+		if i, ok := index[fmt.Sprintf(format.JointName, n)]; ok {
+			m.joint = append(m.joint, i)
+		}
+	}
+
+	seen := make(map[int]bool)
+	for i, h := range header {
+		year, kind, ok := parseHistoryHeader(strings.TrimSpace(h))
+		if !ok {
+			continue
+		}
+		name := fmt.Sprintf("%d%s", year, kind)
+		if _, dup := m.history[name]; dup {
+			continue
+		}
+		m.history[name] = i
+		if !seen[year] {
+			seen[year] = true
+			m.years = append(m.years, year)
+		}
+	}
+	sort.Ints(m.years)
+	return m
+}
+
+// parseHistoryHeader は送受信の履歴列の列名から年と種類 (送 / 受 / 喪中) を取り出す。
+func parseHistoryHeader(h string) (int, string, bool) {
+	var yearStr, kind string
+	if g := historyYearFirst.FindStringSubmatch(h); g != nil {
+		yearStr, kind = g[1], g[2]
+	} else if g := historyYearLast.FindStringSubmatch(h); g != nil {
+		kind, yearStr = g[1], g[2]
+	} else {
+		return 0, "", false
+	}
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		return 0, "", false
+	}
+	return year, strings.TrimSuffix(kind, "信"), true
+}
+
+// Convert は1行を宛先と送受信の履歴に変換する。姓が空の行は false。
+func (m *Mapping) Convert(rec []string) (model.Address, model.History, bool) {
+	get := func(field string) string {
+		var parts []string
+		for _, i := range m.fields[field] {
+			if i < len(rec) {
+				if v := strings.TrimSpace(rec[i]); v != "" {
+					parts = append(parts, v)
+				}
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+
+	addr := model.Address{
+		FamilyName: get(fieldFamily),
+		GivenName:  get(fieldGiven),
+		Honorific:  get(fieldHonorific),
+		PostalCode: postal.Normalize(get(fieldPostal)),
+		Address1:   get(fieldAddress1),
+		Address2:   get(fieldAddress2),
+	}
+	if addr.FamilyName == "" {
+		parts := strings.Fields(strings.ReplaceAll(get(fieldFullName), "　", " "))
+		if len(parts) > 0 {
+			addr.FamilyName = parts[0]
+			addr.GivenName = strings.Join(parts[1:], "")
+		}
+	}
+	if addr.FamilyName == "" {
+		return addr, nil, false
+	}
+
+	if r := get(fieldFamilyRd); r != "" {
+		addr.Reading = strings.TrimSpace(r + " " + get(fieldGivenRd))
+	} else {
+		addr.Reading = strings.Join(strings.Fields(strings.ReplaceAll(get(fieldReading), "　", " ")), " ")
+	}
+
+	for _, i := range m.joint {
+		if i < len(rec) {
+			if v := strings.TrimSpace(rec[i]); v != "" {
+				addr.JointNames = append(addr.JointNames, v)
+			}
+		}
+	}
+	addr.Tags = model.SplitList(get(fieldTags))
+
+	hist := make(model.History)
+	for _, year := range m.years {
+		var st model.YearStatus
+		st.Sent = m.checked(rec, fmt.Sprintf("%d送", year))
+		st.Received = m.checked(rec, fmt.Sprintf("%d受", year))
+		st.Mourning = m.checked(rec, fmt.Sprintf("%d喪中", year))
+		hist[year] = st
+	}
+	return addr, hist, true
+}
+
+func (m *Mapping) checked(rec []string, name string) bool {
+	i, ok := m.history[name]
+	if !ok || i >= len(rec) {
+		return false
+	}
+	v := strings.TrimSpace(rec[i])
+	return v != "" && !falseValues[v]
+}
+
+// Preview はプレビュー用に「変換先の項目 ← 取り込み元の列名」の一覧と、
+// どの項目にも使わない取り込み元の列名を返す。
+func (m *Mapping) Preview() (pairs [][2]string, unused []string) {
+	used := make(map[int]bool)
+	add := func(field string, cols []int) {
+		if len(cols) == 0 {
+			return
+		}
+		var names []string
+		for _, i := range cols {
+			used[i] = true
+			names = append(names, m.Header[i])
+		}
+		pairs = append(pairs, [2]string{field, strings.Join(names, " + ")})
+	}
+	for _, f := range []string{fieldFamily, fieldGiven, fieldFullName, fieldFamilyRd, fieldGivenRd, fieldReading, fieldHonorific, fieldPostal, fieldAddress1, fieldAddress2, fieldTags} {
+		add(f, m.fields[f])
+	}
+	add("連名", m.joint)
+	for _, year := range m.years {
+		for _, kind := range []string{"送", "受", "喪中"} {
+			name := fmt.Sprintf("%d%s", year, kind)
+			if i, ok := m.history[name]; ok {
+				add(name, []int{i})
+			}
+		}
+	}
+	for i, h := range m.Header {
+		if !used[i] && strings.TrimSpace(h) != "" {
+			unused = append(unused, h)
+		}
+	}
+	return pairs, unused
+}
+
+// WriteTSV は変換結果を atena_printer の TSV 形式 (UTF-8) で書き出す。
+// 送受信の列は取り込み元にあった年だけを出力する。
+func (res *Result) WriteTSV(w io.Writer) error {
+	header := []string{"ID", "姓", "名", "よみ", "連名", "敬称", "郵便番号", "住所1", "住所2", "タグ"}
+	for _, year := range res.Mapping.years {
+		header = append(header, fmt.Sprintf("%d送", year), fmt.Sprintf("%d受", year), fmt.Sprintf("%d喪中", year))
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = '\t'
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for i, addr := range res.Addresses {
+		row := []string{
+			addr.ID, addr.FamilyName, addr.GivenName, addr.Reading,
+			strings.Join(addr.JointNames, "、"), addr.Honorific, addr.PostalCode,
+			addr.Address1, addr.Address2, strings.Join(addr.Tags, "、"),
+		}
+		for _, year := range res.Mapping.years {
+			st := res.Histories[i][year]
+			row = append(row, mark(st.Sent), mark(st.Received), mark(st.Mourning))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func mark(b bool) string {
+	if b {
+		return "○"
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+
+	"atena_printer/internal/model"
+	"atena_printer/internal/textenc"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string // 空ならエラー
+	}{
+		{"姓,名,姓フリガナ,名フリガナ,自宅〒,自宅住所1,自宅住所2,自宅住所3,分類", "fudemame"},
+		{"氏名,フリガナ,自宅〒,自宅住所1,自宅住所2,グループ", "fudegurume"},
+		{"姓,名,姓ふりがな,名ふりがな,敬称,自宅郵便番号,自宅住所1", "atenashokunin"},
+		{"姓,名,郵便番号,住所1", ""},
+	}
+	for _, tt := range tests {
+		f, err := Detect(strings.Split(tt.header, ","))
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Detect(%s) = %s, want エラー", tt.header, f.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Detect(%s): %v", tt.header, err)
+			continue
+		}
+		if f.Name != tt.want {
+			t.Errorf("Detect(%s) = %s, want %s", tt.header, f.Name, tt.want)
+		}
+	}
+}
+
+func TestParseHistoryHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		year   int
+		kind   string
+		ok     bool
+	}{
+		{"2025年賀状送信", 2025, "送", true},
+		{"2025送", 2025, "送", true},
+		{"2025年 受信", 2025, "受", true},
+		{"送信2024", 2024, "送", true},
+		{"年賀受信2024年", 2024, "受", true},
+		{"2023喪中", 2023, "喪中", true},
+		{"送付先", 0, "", false},
+		{"25送", 0, "", false},
+	}
+	for _, tt := range tests {
+		year, kind, ok := parseHistoryHeader(tt.header)
+		if year != tt.year || kind != tt.kind || ok != tt.ok {
+			t.Errorf("parseHistoryHeader(%q) = %d, %q, %v, want %d, %q, %v", tt.header, year, kind, ok, tt.year, tt.kind, tt.ok)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	header := strings.Split("姓,名,姓フリガナ,名フリガナ,敬称,自宅〒,自宅住所1,自宅住所2,自宅住所3,分類,連名1,連名2,2025送信,2025受信,2026送", ",")
+	m := NewMapping(Formats[0], header)
+
+	tests := []struct {
+		name string
+		rec  string
+		addr model.Address
+		hist model.History
+		ok   bool
+	}{
+		{
+			name: "すべての項目",
+			rec:  "佐藤,花子,サトウ,ハナコ,様,〒150-0001,東京都渋谷区神宮前3-1-2,サンプル,101号室,家族、友人 仕事,太郎,,○,×,1",
+			addr: model.Address{
+				FamilyName: "佐藤", GivenName: "花子", Reading: "サトウ ハナコ", Honorific: "様",
+				PostalCode: "1500001", Address1: "東京都渋谷区神宮前3-1-2", Address2: "サンプル 101号室",
+				JointNames: []string{"太郎"}, Tags: []string{"家族", "友人 仕事"},
+			},
+			hist: model.History{2025: {Sent: true}, 2026: {Sent: true}},
+			ok:   true,
+		},
+		{
+			name: "列が足りない行",
+			rec:  "鈴木,一郎",
+			addr: model.Address{FamilyName: "鈴木", GivenName: "一郎"},
+			hist: model.History{2025: {}, 2026: {}},
+			ok:   true,
+		},
+		{
+			name: "姓が空",
+			rec:  ",一郎,,,,1000001",
+			ok:   false,
+		},
+	}
+	for _, tt := range tests {
+		addr, hist, ok := m.Convert(strings.Split(tt.rec, ","))
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(addr, tt.addr) {
+			t.Errorf("%s:\n got  %+v\n want %+v", tt.name, addr, tt.addr)
+		}
+		if !reflect.DeepEqual(hist, tt.hist) {
+			t.Errorf("%s: 履歴 = %v, want %v", tt.name, hist, tt.hist)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	csv := "氏名,フリガナ,自宅〒,自宅住所1,自宅住所2,グループ\r\n" +
+		"高橋　美咲,タカハシ　ミサキ,980-0001,宮城県仙台市青葉区中央1-1,,仕事\r\n" +
+		",,,,,\r\n" +
+		",,1000001,東京都,,\r\n"
+	sjis, err := japanese.ShiftJIS.NewEncoder().String(csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fudegurume.csv")
+	if err := os.WriteFile(path, []byte(sjis), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ReadFile(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Mapping.Format.Name != "fudegurume" || res.Encoding != textenc.CP932 {
+		t.Errorf("形式 = %s / %s, want fudegurume / %s", res.Mapping.Format.Name, res.Encoding, textenc.CP932)
+	}
+	if res.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", res.Skipped)
+	}
+	want := []model.Address{{
+		FamilyName: "高橋", GivenName: "美咲", Reading: "タカハシ ミサキ", PostalCode: "9800001",
+		Address1: "宮城県仙台市青葉区中央1-1", Tags: []string{"仕事"},
+	}}
+	if !reflect.DeepEqual(res.Addresses, want) {
+		t.Errorf("Addresses =\n %+v\nwant\n %+v", res.Addresses, want)
+	}
+
+	if _, err := ReadFile(path, "unknown"); err == nil {
+		t.Error("ReadFile(-from unknown) がエラーになりません")
+	}
+}
@@ -0,0 +1,150 @@
+// Package journal はシートへの書き込みをローカルファイルに記録し、undo で元に戻せるようにする。
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Change は1セル分の書き込み。Range はシートでは「シート名!A1」形式、SQLite では「ID/年/種類」形式。
+// シートでは行の挿入や並べ替えの後でも同じセルを取り消せるよう、行の ID (無ければ氏名) と列のヘッダも記録する。
+type Change struct {
+	Range  string `json:"range"`
+	ID     string `json:"id,omitempty"`     // 行の ID (ID 列の値)
+	Name   string `json:"name,omitempty"`   // ID の無い行の「姓 名」
+	Column string `json:"column,omitempty"` // 列のヘッダ
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+// Operation は1回のコマンド実行で行った書き込みの記録。
+type Operation struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Target  string    `json:"target"` // 書き込み先 (スプレッドシートID/シート名 など)
+	Undoes  string    `json:"undoes,omitempty"`
+	Changes []Change  `json:"changes"`
+}
+
+// Journal は JSON Lines 形式の記録ファイル。
+type Journal struct {
+	path string
+}
+
+func Open(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Append は操作を記録ファイルの末尾に追加する。ID と時刻が空なら設定する。
+func (j *Journal) Append(op *Operation) error {
+	if op.Time.IsZero() {
+		op.Time = time.Now()
+	}
+	if op.ID == "" {
+		b := make([]byte, 2)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("操作IDの生成に失敗: %w", err)
+		}
+		op.ID = op.Time.Format("20060102-150405") + "-" + hex.EncodeToString(b)
+	}
+
+	line, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("書き込み記録を開けません: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("書き込み記録の保存に失敗: %w", err)
+	}
+	return nil
+}
+
+// Abort は書き込みに失敗した操作を取り消し済みとして記録し、undo の対象から外す。
+// 書き込みの前に Append で記録しておき、失敗した場合に呼ぶ。
+func (j *Journal) Abort(op *Operation) error {
+	return j.Append(&Operation{Command: op.Command + " (失敗)", Target: op.Target, Undoes: op.ID})
+}
+
+// Operations は記録されているすべての操作を古い順に返す。ファイルが無ければ空。
+func (j *Journal) Operations() ([]Operation, error) {
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("書き込み記録を開けません: %w", err)
+	}
+	defer f.Close()
+
+	var ops []Operation
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var op Operation
+		if err := json.Unmarshal(sc.Bytes(), &op); err != nil {
+			return nil, fmt.Errorf("書き込み記録の %d 行目が不正です: %w", n, err)
+		}
+		ops = append(ops, op)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("書き込み記録の読み込みに失敗: %w", err)
+	}
+	return ops, nil
+}
+
+// Undone は取り消し済みの操作IDの集合を返す。
+func Undone(ops []Operation) map[string]bool {
+	undone := make(map[string]bool)
+	for _, op := range ops {
+		if op.Undoes != "" {
+			undone[op.Undoes] = true
+		}
+	}
+	return undone
+}
+
+// Find は id の操作を返す。id が空なら、まだ取り消していない最後の操作 (undo 自体は除く) を返す。
+func (j *Journal) Find(id string) (*Operation, error) {
+	ops, err := j.Operations()
+	if err != nil {
+		return nil, err
+	}
+	undone := Undone(ops)
+
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if id != "" {
+			if op.ID != id {
+				continue
+			}
+			if undone[op.ID] {
+				return nil, fmt.Errorf("操作 %s は取り消し済みです", id)
+			}
+			return &op, nil
+		}
+		if op.Undoes == "" && !undone[op.ID] {
+			return &op, nil
+		}
+	}
+
+	if id != "" {
+		return nil, fmt.Errorf("操作 %s が書き込み記録にありません", id)
+	}
+	return nil, fmt.Errorf("取り消せる操作がありません")
+}
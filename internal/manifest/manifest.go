@@ -0,0 +1,91 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"atena_printer/internal/model"
+)
+
+// Entry は印刷した1件分の記録。
+type Entry struct {
+	ID   string `json:"id,omitempty"`
+	Row  int    `json:"row"`
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+// Manifest は generate で印刷した宛先の一覧。mark-sent はこれを元に更新対象を決める。
+type Manifest struct {
+	Year        int       `json:"year"`
+	Mode        string    `json:"mode,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+	OutputFile  string    `json:"output_file"`
+	Entries     []Entry   `json:"entries"`
+}
+
+// New は印刷対象の宛先からマニフェストを作る。
+func New(year int, outputFile string, addresses []model.Address) *Manifest {
+	m := &Manifest{
+		Year:        year,
+		GeneratedAt: time.Now(),
+		OutputFile:  outputFile,
+	}
+	for _, addr := range addresses {
+		m.Entries = append(m.Entries, Entry{
+			ID:   addr.ID,
+			Row:  addr.Row,
+			Name: addr.FamilyName + " " + addr.GivenName,
+			Hash: Hash(addr),
+		})
+	}
+	return m
+}
+
+// Load はマニフェストファイルを読み込む。
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("マニフェストを読み込めません: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("マニフェストの形式が不正です: %w", err)
+	}
+	return &m, nil
+}
+
+// Save はマニフェストをファイルに書き出す。
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// PathFor は PDF の出力パスに対応するマニフェストのパスを返す (nenga.pdf → nenga.manifest.json)。
+func PathFor(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".manifest.json"
+}
+
+// Hash は宛名面に印刷される項目から行データのハッシュを計算する。
+func Hash(addr model.Address) string {
+	fields := []string{
+		addr.FamilyName,
+		addr.GivenName,
+		strings.Join(addr.JointNames, ","),
+		addr.Honorific,
+		addr.PostalCode,
+		addr.Address1,
+		addr.Address2,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
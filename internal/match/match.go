@@ -0,0 +1,121 @@
+package match
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"atena_printer/internal/model"
+)
+
+// minScore より低い類似度の候補は返さない。
+const minScore = 0.6
+
+// Candidate は名前の照合結果の1件。
+type Candidate struct {
+	Index   int     // 照合対象スライス内の位置
+	Score   float64 // 1.0 が完全一致
+	Matched string  // 一致した表記 (姓名・連名・よみ)
+}
+
+// Find は query に似た名前を持つ宛先を類似度の高い順に返す。
+// 姓名・姓＋連名・よみを照合し、姓だけの入力は同姓の全員を候補にする。
+func Find(query string, addresses []model.Address) []Candidate {
+	q := normalize(query)
+	if q == "" {
+		return nil
+	}
+
+	var result []Candidate
+	for i, addr := range addresses {
+		best := Candidate{Index: i}
+		for _, key := range keys(addr) {
+			s := similarity(q, normalize(key))
+			if s > best.Score {
+				best.Score = s
+				best.Matched = key
+			}
+		}
+		if normalize(addr.FamilyName) == q && best.Score < 0.8 {
+			best.Score = 0.8
+			best.Matched = addr.FamilyName
+		}
+		if best.Score >= minScore {
+			result = append(result, best)
+		}
+	}
+
+	sort.SliceStable(result, func(a, b int) bool {
+		return result[a].Score > result[b].Score
+	})
+	return result
+}
+
+// Unique は候補が確認なしで確定できる (完全一致が1件だけ) かどうかを返す。
+func Unique(cands []Candidate) bool {
+	if len(cands) == 0 || cands[0].Score < 1 {
+		return false
+	}
+	return len(cands) == 1 || cands[1].Score < 1
+}
+
+func keys(addr model.Address) []string {
+	keys := []string{addr.FamilyName + addr.GivenName}
+	for _, jn := range addr.JointNames {
+		keys = append(keys, addr.FamilyName+jn)
+	}
+	if addr.Reading != "" {
+		keys = append(keys, addr.Reading)
+	}
+	return keys
+}
+
+// normalize は空白を除き、全角英数を半角に、カタカナをひらがなにそろえる。
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r) || r == '・':
+			continue
+		case r >= '！' && r <= '～':
+			r = r - '！' + '!'
+		case r >= 'ァ' && r <= 'ヶ':
+			r = r - 'ァ' + 'ぁ'
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// similarity は編集距離から 0〜1 の類似度を計算する。
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
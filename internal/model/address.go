@@ -1,14 +1,19 @@
 package model
 
+import "strings"
+
 type Address struct {
+	ID         string // 行の並べ替えに影響されない識別子 (ID列、任意)
 	FamilyName string
 	GivenName  string
+	Reading    string   // よみがな (任意)
 	JointNames []string // 連名
 	Honorific  string   // 敬称 (default: 様)
 	PostalCode string   // ハイフンなし7桁
 	Address1   string
 	Address2   string
-	Row        int // スプレッドシート上の行番号 (1-indexed)
+	Tags       []string // タグ (グループ分け、任意)
+	Row        int      // スプレッドシート上の行番号 (1-indexed)
 }
 
 type YearStatus struct {
@@ -16,3 +21,32 @@ type YearStatus struct {
 	Received bool // もらった
 	Mourning bool // 喪中
 }
+
+// AddressFill は complete で宛先の空欄に書き込む郵便番号・住所。空の項目は書き込まない。
+type AddressFill struct {
+	Target     Address
+	PostalCode string // 7桁の郵便番号 (シートには「123-4567」の形で書き込む)
+	Address1   string
+	Address2   string
+}
+
+// History は1件分の年ごとのステータス (キーは西暦)。
+type History map[int]YearStatus
+
+// SplitList はカンマ・読点・改行区切りの値を分割する (連名・タグ列で共用)。
+func SplitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "、", ",")
+	s = strings.ReplaceAll(s, "\n", ",")
+	parts := strings.Split(s, ",")
+	var names []string
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			names = append(names, p)
+		}
+	}
+	return names
+}
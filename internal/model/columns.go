@@ -0,0 +1,8 @@
+package model
+
+// NewYearColumns は new-year で追加する年ステータス列。
+type NewYearColumns struct {
+	Headers []string // 追加する列のヘッダ (既にある列は含まない)
+	At      int      // 挿入する列位置 (0-indexed)
+	After   string   // 直前の列のヘッダ (先頭に挿入する場合は空)
+}
@@ -0,0 +1,36 @@
+package pdf
+
+import (
+	"strconv"
+	"strings"
+
+	"atena_printer/internal/config"
+)
+
+// AddBackPage は裏面 (文面) のページを追加する。
+func (g *Generator) AddBackPage(tmpl config.BackTemplate, year int) {
+	g.pdf.AddPage()
+
+	r := strings.NewReplacer(
+		"{year}", strconv.Itoa(year),
+		"{prev_year}", strconv.Itoa(year-1),
+	)
+
+	g.drawVerticalText(backTitleX, backTitleY, r.Replace(tmpl.Title), backTitleSize, backLimit)
+
+	x := backBodyX
+	for _, line := range tmpl.Lines {
+		g.drawVerticalText(x, backBodyY, r.Replace(line), backBodySize, backLimit)
+		x -= backLineSpacing
+	}
+
+	// 本文と日付の間は1行空ける
+	if tmpl.Date != "" {
+		x -= backLineSpacing
+		g.drawVerticalText(x, backBodyY+backBodySize*ptToMM*1.3, r.Replace(tmpl.Date), backBodySize, backLimit)
+		x -= backLineSpacing
+	}
+
+	senderName := g.sender.FamilyName + g.sender.GivenName
+	g.drawVerticalText(x, backSenderY, senderName, backBodySize, backLimit)
+}
@@ -6,6 +6,7 @@ import (
 
 	"atena_printer/internal/config"
 	"atena_printer/internal/model"
+	"atena_printer/internal/postal"
 
 	"github.com/signintech/gopdf"
 )
@@ -18,6 +19,12 @@ type Generator struct {
 }
 
 func NewGenerator(fontFile, postalFontFile string, sender config.Sender) (*Generator, error) {
+	code, _, err := postal.Parse(sender.PostalCode)
+	if err != nil {
+		return nil, fmt.Errorf("sender.postal_code が不正です: %w", err)
+	}
+	sender.PostalCode = code
+
 	p := &gopdf.GoPdf{}
 	p.Start(gopdf.Config{
 		PageSize: gopdf.Rect{W: HagakiWidth, H: HagakiHeight},
@@ -46,6 +53,14 @@ func NewGenerator(fontFile, postalFontFile string, sender config.Sender) (*Gener
 
 // AddPage は1人分の宛名ページを追加する
 func (g *Generator) AddPage(addr model.Address) error {
+	// 桁がずれて別の枠に印字されないよう、7桁でない郵便番号は受け付けない
+	if !postal.Valid(addr.PostalCode) {
+		if addr.PostalCode == "" {
+			return fmt.Errorf("郵便番号が空です")
+		}
+		return fmt.Errorf("郵便番号 %s が7桁の数字ではありません", addr.PostalCode)
+	}
+
 	g.pdf.AddPage()
 
 	// 宛先郵便番号
@@ -61,8 +76,7 @@ func (g *Generator) AddPage(addr model.Address) error {
 	g.drawRecipientName(addr)
 
 	// 差出人郵便番号
-	senderPostal := normalizePostal(g.sender.PostalCode)
-	g.drawPostalCode(senderPostal, senderPostalX[:], senderPostalY, senderPostalSize)
+	g.drawPostalCode(g.sender.PostalCode, senderPostalX[:], senderPostalY, senderPostalSize)
 
 	// 差出人住所
 	g.drawVerticalText(senderAddr1X, senderAddrY, g.sender.Address1, senderAddrSize, senderAddrLimit)
@@ -193,16 +207,3 @@ func (g *Generator) drawVerticalText(x, startY float64, text string, fontSize fl
 		y += charHeight
 	}
 }
-
-func normalizePostal(code string) string {
-	result := make([]rune, 0, len(code))
-	for _, r := range code {
-		switch {
-		case r >= '0' && r <= '9':
-			result = append(result, r)
-		case r >= '０' && r <= '９':
-			result = append(result, r-'０'+'0')
-		}
-	}
-	return string(result)
-}
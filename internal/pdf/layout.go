@@ -54,3 +54,16 @@ const (
 	senderNameSize  = 10.0  // 差出人名前のフォントサイズ (pt)
 	senderNameLimit = 116.0 // 差出人名前の下限 Y (mm)
 )
+
+// 裏面 (文面) の位置。右の列から左へ順に書く
+const (
+	backTitleX      = 84.0  // 見出しの X (mm)
+	backTitleY      = 14.0  // 見出しの開始 Y (mm)
+	backTitleSize   = 20.0  // 見出しのフォントサイズ (pt)
+	backBodyX       = 68.0  // 本文1行目の X (mm)
+	backBodyY       = 22.0  // 本文の開始 Y (mm)
+	backBodySize    = 12.0  // 本文のフォントサイズ (pt)
+	backLineSpacing = 8.0   // 本文の行間隔 (mm)
+	backSenderY     = 80.0  // 差出人名前の開始 Y (mm)
+	backLimit       = 136.0 // 文面の下限 Y (mm)
+)
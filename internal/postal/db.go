@@ -0,0 +1,468 @@
+package postal
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/width"
+
+	"atena_printer/internal/model"
+	"atena_printer/internal/textenc"
+)
+
+// Entry は郵便番号データの1件。
+type Entry struct {
+	Code   string
+	Pref   string // 都道府県
+	City   string // 市区町村 (郡を含む)
+	Town   string // 町域 (括弧書きを除く。空なら市区町村内の他に掲載の無い地域)
+	Office string // 事業所名 (JIGYOSYO.CSV の大口事業所の場合)
+	Street string // 事業所の小字・丁目・番地
+
+	// building は高層ビルの郵便番号 (町域が「町名 + ビル名」) の場合のビル名、base はその町名部分。
+	building string
+	base     string
+	// n は住所との照合用に表記をそろえた値。
+	n struct{ pref, city, county, town, base, building string }
+}
+
+// normalize は住所との照合用の値を用意する。
+func (e *Entry) normalize() {
+	e.n.pref = normalizeAddress(e.Pref)
+	e.n.city = normalizeAddress(e.City)
+	if i := strings.Index(e.n.city, "郡"); i >= 0 {
+		e.n.county = e.n.city[i+len("郡"):]
+	}
+	e.n.town = normalizeAddress(e.Town)
+	e.n.base = normalizeAddress(e.base)
+	e.n.building = normalizeAddress(e.building)
+}
+
+// Area は「都道府県 市区町村 町域」の表示。
+func (e Entry) Area() string {
+	s := e.Pref + e.City + e.Town
+	if e.Office != "" {
+		s += e.Street + " " + e.Office
+	}
+	return s
+}
+
+// Address は郵便番号から分かる範囲の住所 (番地は含まない) を住所1と住所2に分けて返す。
+// 高層ビルの郵便番号ではビル名を住所2にする。事業所の個別番号では小字・番地まで住所1に含める。
+func (e Entry) Address() (address1, address2 string) {
+	switch {
+	case e.building != "" && e.base != "":
+		return e.Pref + e.City + e.base, e.building
+	case e.Office != "":
+		return e.Pref + e.City + e.Town + e.Street, ""
+	}
+	return e.Pref + e.City + e.Town, ""
+}
+
+// DB は日本郵便の郵便番号データ (KEN_ALL.CSV・JIGYOSYO.CSV) の索引。
+type DB struct {
+	byCode map[string][]Entry
+	// areas は都道府県 → 市区町村 → 一般の郵便番号 (事業所以外)。住所からの検索に使う。
+	areas map[string]map[string][]Entry
+	prefs []string
+}
+
+// Load は郵便番号データのファイルを読み込む。KEN_ALL.CSV (住所の郵便番号) と JIGYOSYO.CSV (事業所の個別番号) に対応し、
+// 列数から種類を判定する。Shift_JIS / UTF-8 のどちらでもよく、日本郵便が配布している ZIP ファイルのままでもよい。
+func Load(paths ...string) (*DB, error) {
+	db := &DB{
+		byCode: make(map[string][]Entry),
+		areas:  make(map[string]map[string][]Entry),
+	}
+	for _, path := range paths {
+		if err := db.load(path); err != nil {
+			return nil, fmt.Errorf("郵便番号データ %s の読み込みに失敗: %w", path, err)
+		}
+	}
+	if len(db.byCode) == 0 {
+		return nil, fmt.Errorf("郵便番号データが空です")
+	}
+	db.linkBuildings()
+	for pref, cities := range db.areas {
+		db.prefs = append(db.prefs, pref)
+		for _, entries := range cities {
+			for i := range entries {
+				entries[i].normalize()
+			}
+		}
+	}
+	for _, entries := range db.byCode {
+		for i := range entries {
+			entries[i].normalize()
+		}
+	}
+	return db, nil
+}
+
+func (db *DB) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		if data, err = readZippedCSV(data); err != nil {
+			return err
+		}
+	}
+	text, _, err := textenc.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	r := csv.NewReader(strings.NewReader(text))
+	r.FieldsPerRecord = -1
+	var pending *Entry // 町域が複数行に分かれている行
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch len(rec) {
+		case 15: // KEN_ALL.CSV
+			code, town := rec[2], rec[8]
+			if pending != nil {
+				if code == pending.Code {
+					pending.Town += town
+					if !strings.Contains(pending.Town, "（") || strings.Contains(pending.Town, "）") {
+						db.add(*pending)
+						pending = nil
+					}
+					continue
+				}
+				db.add(*pending)
+				pending = nil
+			}
+			e := Entry{Code: code, Pref: rec[6], City: rec[7], Town: town}
+			if strings.Contains(town, "（") && !strings.Contains(town, "）") {
+				pending = &e
+				continue
+			}
+			db.add(e)
+		case 13: // JIGYOSYO.CSV
+			db.add(Entry{Code: rec[7], Pref: rec[3], City: rec[4], Town: rec[5], Office: rec[2], Street: rec[6]})
+		default:
+			return fmt.Errorf("%d行目: KEN_ALL.CSV (15列)・JIGYOSYO.CSV (13列) の形式ではありません (%d列)", line, len(rec))
+		}
+	}
+	if pending != nil {
+		db.add(*pending)
+	}
+	return nil
+}
+
+// readZippedCSV は ZIP ファイル内の最初の CSV を返す。
+func readZippedCSV(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if !strings.EqualFold(filepath.Ext(f.Name), ".csv") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("ZIP ファイルに CSV がありません")
+}
+
+func (db *DB) add(e Entry) {
+	if !Valid(e.Code) {
+		return
+	}
+	town := e.Town
+	if e.Office == "" {
+		// 「（１階）」「（地階・階層不明）」は高層ビルの郵便番号
+		if i := strings.Index(town, "（"); i >= 0 && strings.Contains(town[i:], "階") {
+			e.building = town[:i]
+		}
+		town = cleanTown(town)
+	}
+	e.Town = town
+
+	db.byCode[e.Code] = append(db.byCode[e.Code], e)
+	if e.Office != "" {
+		return
+	}
+	cities := db.areas[e.Pref]
+	if cities == nil {
+		cities = make(map[string][]Entry)
+		db.areas[e.Pref] = cities
+	}
+	cities[e.City] = append(cities[e.City], e)
+}
+
+// cleanTown は KEN_ALL.CSV の町域から括弧書きと「以下に掲載がない場合」などの注記を除く。
+func cleanTown(town string) string {
+	if i := strings.Index(town, "（"); i >= 0 {
+		town = town[:i]
+	}
+	switch {
+	case town == "以下に掲載がない場合",
+		strings.HasSuffix(town, "の次に番地がくる場合"),
+		strings.HasSuffix(town, "一円") && town != "一円":
+		return ""
+	}
+	return town
+}
+
+// linkBuildings は高層ビルの郵便番号の町域を、同じ市区町村の町名とビル名に分ける。
+func (db *DB) linkBuildings() {
+	for _, cities := range db.areas {
+		for city, entries := range cities {
+			for i, e := range entries {
+				if e.building == "" {
+					continue
+				}
+				for _, other := range entries {
+					if other.building == "" && other.Town != "" && strings.HasPrefix(e.building, other.Town) && len(other.Town) > len(e.base) {
+						e.base = other.Town
+					}
+				}
+				e.building = strings.TrimPrefix(e.building, e.base)
+				entries[i] = e
+			}
+			cities[city] = entries
+		}
+	}
+	for code, entries := range db.byCode {
+		for i, e := range entries {
+			if e.building != "" {
+				for _, a := range db.areas[e.Pref][e.City] {
+					if a.Code == e.Code && a.Town == e.Town && a.building != "" {
+						entries[i] = a
+						break
+					}
+				}
+			}
+		}
+		db.byCode[code] = entries
+	}
+}
+
+// Lookup は郵便番号に対応する地域 (事業所を含む) を返す。
+func (db *DB) Lookup(code string) []Entry {
+	return db.byCode[code]
+}
+
+// Match は住所が郵便番号データの地域 e に含まれるかどうかを返す。
+func Match(address string, e Entry) bool {
+	if e.n.city == "" {
+		e.normalize()
+	}
+	rest, ok := trimArea(normalizeAddress(address), e)
+	return ok && matchTown(rest, e)
+}
+
+// matchTown は都道府県と市区町村を除いた住所 rest が e の町域に含まれるかどうかを返す。
+func matchTown(rest string, e Entry) bool {
+	switch {
+	case e.building != "":
+		return strings.HasPrefix(rest, e.n.base) && strings.Contains(rest, e.n.building)
+	case e.n.town == "":
+		return true
+	case strings.HasPrefix(e.City, "京都市"):
+		// 京都市は「〇〇通△△上る」などの通り名が町名の前に付く
+		return strings.Contains(rest, e.n.town)
+	}
+	return strings.HasPrefix(rest, e.n.town)
+}
+
+// trimArea は住所 (normalizeAddress 済み) から e の都道府県と市区町村を取り除く。
+// 都道府県と郡は省略されていてもよい。
+func trimArea(address string, e Entry) (string, bool) {
+	if rest, ok := strings.CutPrefix(address, e.n.pref); ok {
+		address = rest
+	} else if model.Prefecture(address) != "" {
+		return "", false
+	}
+	if rest, ok := strings.CutPrefix(address, e.n.city); ok {
+		return rest, true
+	}
+	if e.n.county != "" {
+		if rest, ok := strings.CutPrefix(address, e.n.county); ok {
+			return rest, true
+		}
+	}
+	return "", false
+}
+
+// Find は住所に対応する郵便番号を探す。市区町村と町域が最も長く一致する地域を返す
+// (同じ町域で番地により郵便番号が分かれている場合は複数)。事業所の個別番号は返さない。
+func (db *DB) Find(address string) []Entry {
+	addr := normalizeAddress(address)
+	prefs := db.prefs
+	if p := model.Prefecture(addr); p != "" {
+		prefs = []string{p}
+	}
+
+	var best []Entry
+	bestLen := -1
+	for _, pref := range prefs {
+		for _, entries := range db.areas[pref] {
+			// 同じ市区町村の地域は都道府県・市区町村が共通なので、照合は1回でよい
+			rest, ok := trimArea(addr, entries[0])
+			if !ok {
+				continue
+			}
+			for _, e := range entries {
+				if !matchTown(rest, e) {
+					continue
+				}
+				n := len(e.City) + len(e.Town) + len(e.building)
+				switch {
+				case n > bestLen:
+					best, bestLen = []Entry{e}, n
+				case n == bestLen:
+					best = append(best, e)
+				}
+			}
+		}
+	}
+	sort.SliceStable(best, func(i, j int) bool { return best[i].Code < best[j].Code })
+	return uniqueCodes(best)
+}
+
+func uniqueCodes(entries []Entry) []Entry {
+	var out []Entry
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !seen[e.Code] {
+			seen[e.Code] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+var kanaFold = strings.NewReplacer("ヶ", "ケ", "ヵ", "ケ", "大字", "", " ", "", "　", "")
+
+// normalizeAddress は住所の表記ゆれ (全角/半角、ヶ/ケ、大字、漢数字の条・丁目) をそろえる。
+func normalizeAddress(s string) string {
+	s = width.Fold.String(s)
+	s = kanaFold.Replace(s)
+	return kanjiNumbers(s)
+}
+
+var kanjiDigits = map[rune]int{'一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+
+// kanjiNumbers は「北一条」「三丁目」「二十線」の漢数字を算用数字にする (地名の「一宮」などは変えない)。
+func kanjiNumbers(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && (kanjiDigits[runes[j]] > 0 || runes[j] == '十') {
+			j++
+		}
+		if j > i && hasNumberSuffix(runes[j:]) {
+			if n, ok := parseKanjiNumber(runes[i:j]); ok {
+				fmt.Fprint(&b, n)
+				i = j
+				continue
+			}
+		}
+		if j == i {
+			j = i + 1
+		}
+		b.WriteString(string(runes[i:j]))
+		i = j
+	}
+	return b.String()
+}
+
+func hasNumberSuffix(rest []rune) bool {
+	s := string(rest)
+	for _, suffix := range []string{"条", "丁目", "線", "番町", "地割"} {
+		if strings.HasPrefix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKanjiNumber は 1〜99 の漢数字 (一、十、二十三 など) を読む。
+func parseKanjiNumber(r []rune) (int, bool) {
+	n, cur := 0, 0
+	for _, c := range r {
+		if c == '十' {
+			if cur == 0 {
+				cur = 1
+			}
+			n += cur * 10
+			cur = 0
+			continue
+		}
+		if cur != 0 {
+			return 0, false
+		}
+		cur = kanjiDigits[c]
+	}
+	return n + cur, n+cur > 0 && n+cur < 100
+}
+
+// Mismatch は郵便番号と住所の食い違い。
+type Mismatch struct {
+	Reason      string
+	Areas       []Entry // 郵便番号の地域
+	Suggestions []Entry // 住所から探した郵便番号
+}
+
+// Check は郵便番号 code が住所の地域のものか確かめ、食い違っていれば住所から探した郵便番号を添えて返す。
+// 住所が空の場合と食い違いが無い場合は nil を返す。
+func (db *DB) Check(code, address string) *Mismatch {
+	if strings.TrimSpace(address) == "" {
+		return nil
+	}
+	suggest := func() []Entry {
+		var out []Entry
+		for _, e := range db.Find(address) {
+			if e.Code != code {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+
+	switch {
+	case code == "":
+		return &Mismatch{Reason: "郵便番号が空です", Suggestions: suggest()}
+	case !Valid(code):
+		return &Mismatch{Reason: fmt.Sprintf("郵便番号 %s が7桁ではありません", code), Suggestions: suggest()}
+	}
+
+	areas := db.Lookup(code)
+	if len(areas) == 0 {
+		return &Mismatch{Reason: fmt.Sprintf("郵便番号 %s は郵便番号データにありません", Format(code)), Suggestions: suggest()}
+	}
+	for _, e := range areas {
+		if Match(address, e) {
+			return nil
+		}
+	}
+	return &Mismatch{
+		Reason:      fmt.Sprintf("住所が 〒%s の地域と一致しません", Format(code)),
+		Areas:       areas,
+		Suggestions: suggest(),
+	}
+}
@@ -0,0 +1,121 @@
+package postal
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// kenAll は KEN_ALL.CSV と同じ15列の行を作る。
+func kenAll(code, pref, city, town string) string {
+	return `01101,"060  ","` + code + `","ﾎｯｶｲﾄﾞｳ","ｻｯﾎﾟﾛｼ","ｶﾅ","` + pref + `","` + city + `","` + town + `",0,0,0,0,0,0` + "\r\n"
+}
+
+// writeTestKenAll は日本郵便の配布形式 (Shift_JIS の CSV を ZIP にしたもの) の KEN_ALL を作る。
+func writeTestKenAll(t *testing.T, dir string) string {
+	t.Helper()
+	csv := kenAll("0600000", "北海道", "札幌市中央区", "以下に掲載がない場合") +
+		kenAll("0600042", "北海道", "札幌市中央区", "大通西（１～１９丁目）") +
+		kenAll("0640941", "北海道", "札幌市中央区", "旭ケ丘") +
+		kenAll("9960301", "山形県", "最上郡大蔵村", "南山（４３０番地以上「１７７０－１～２、１８６２－４２、") +
+		kenAll("9960301", "山形県", "最上郡大蔵村", "１９２３－５」を除く）、大蔵村一円") +
+		kenAll("1000005", "東京都", "千代田区", "丸の内") +
+		kenAll("1006390", "東京都", "千代田区", "丸の内ＪＰタワー（地階・階層不明）")
+	sjis, err := japanese.ShiftJIS.NewEncoder().String(csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "ken_all.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("KEN_ALL.CSV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(sjis)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadKenAll(t *testing.T) {
+	dir := t.TempDir()
+	kenAllPath := writeTestKenAll(t, dir)
+	jigyosyo := filepath.Join(dir, "JIGYOSYO.CSV")
+	office := `13101,"ﾆﾂﾎﾟﾝﾕｳｾｲ","日本郵政　株式会社","東京都","千代田区","大手町","２丁目３－１","1008798","100  ","銀座",0,0,0` + "\r\n"
+	if err := os.WriteFile(jigyosyo, []byte(office), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Load(kenAllPath, jigyosyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		code       string
+		area       string
+		addr1      string
+		addr2      string
+		matchAddr  string
+		matchWants bool
+	}{
+		{"0600000", "北海道札幌市中央区", "北海道札幌市中央区", "", "北海道札幌市中央区宮の森", true},
+		{"0600042", "北海道札幌市中央区大通西", "北海道札幌市中央区大通西", "", "札幌市中央区大通西三丁目", true},
+		{"0640941", "北海道札幌市中央区旭ケ丘", "北海道札幌市中央区旭ケ丘", "", "北海道札幌市中央区旭ヶ丘1-2", true},
+		{"9960301", "山形県最上郡大蔵村南山", "山形県最上郡大蔵村南山", "", "山形県大蔵村南山500", true},
+		{"1006390", "東京都千代田区丸の内ＪＰタワー", "東京都千代田区丸の内", "ＪＰタワー", "東京都千代田区丸の内2-7-2 JPタワー", true},
+		{"1000005", "東京都千代田区丸の内", "東京都千代田区丸の内", "", "東京都千代田区大手町1", false},
+		{"1008798", "東京都千代田区大手町２丁目３－１ 日本郵政　株式会社", "東京都千代田区大手町２丁目３－１", "", "東京都千代田区大手町2-3-1", true},
+	}
+	for _, tt := range tests {
+		entries := db.Lookup(tt.code)
+		if len(entries) != 1 {
+			t.Errorf("Lookup(%s) = %d件, want 1件", tt.code, len(entries))
+			continue
+		}
+		e := entries[0]
+		if got := e.Area(); got != tt.area {
+			t.Errorf("Lookup(%s).Area() = %q, want %q", tt.code, got, tt.area)
+		}
+		if a1, a2 := e.Address(); a1 != tt.addr1 || a2 != tt.addr2 {
+			t.Errorf("Lookup(%s).Address() = %q, %q, want %q, %q", tt.code, a1, a2, tt.addr1, tt.addr2)
+		}
+		if got := Match(tt.matchAddr, e); got != tt.matchWants {
+			t.Errorf("Match(%q, %s) = %v, want %v", tt.matchAddr, tt.code, got, tt.matchWants)
+		}
+	}
+
+	var codes []string
+	for _, e := range db.Find("東京都千代田区丸の内二丁目") {
+		codes = append(codes, e.Code)
+	}
+	if got := strings.Join(codes, ","); got != "1000005" {
+		t.Errorf("Find = %s, want 1000005", got)
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.csv")
+	if err := os.WriteFile(path, []byte("a,b,c\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "1行目") {
+		t.Errorf("Load = %v, want 1行目のエラー", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package postal は日本の郵便番号 (7桁) を扱う。
+package postal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dashes は郵便番号の区切りとして使われるハイフン・長音記号の類。
+const dashes = "-‐‑‒–—―−－ーｰ"
+
+// Parse はセルの値を7桁の郵便番号に正規化する。
+// 全角数字・〒・ハイフン・空白を取り除き、数値として保存された値 (600001.0 や 6.00001E+05) も読む。
+// 区切りの無い6桁は数値として保存されて先頭の0が落ちたものとみなし、0を補って fixed を true にする。
+// 7桁にならない場合は err を返す (code には取り除いた後の値が入る)。空の値は code も err も空。
+func Parse(s string) (code string, fixed bool, err error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "　", " "))
+	s = strings.TrimSpace(strings.TrimPrefix(s, "〒"))
+	if s == "" {
+		return "", false, nil
+	}
+
+	var b strings.Builder
+	separated := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r >= '０' && r <= '９':
+			b.WriteRune(r - '０' + '0')
+		case strings.ContainsRune(dashes, r) || r == ' ':
+			separated = true
+		case r == '.' || r == 'e' || r == 'E' || r == '+':
+			// 表計算ソフトの数値表記 (下で解釈する)
+			b.WriteRune(r)
+		default:
+			return s, false, fmt.Errorf("郵便番号 %s に数字以外の文字があります", s)
+		}
+	}
+	code = b.String()
+
+	if strings.ContainsAny(code, ".eE+") {
+		f, perr := strconv.ParseFloat(code, 64)
+		if perr != nil || separated || f < 0 || f != float64(int64(f)) {
+			return s, false, fmt.Errorf("郵便番号 %s を数字として読めません", s)
+		}
+		code = strconv.FormatInt(int64(f), 10)
+	}
+
+	if len(code) == 6 && !separated {
+		return "0" + code, true, nil
+	}
+	if len(code) != 7 {
+		return code, false, fmt.Errorf("郵便番号 %s が7桁ではありません (%d桁)", s, len(code))
+	}
+	return code, false, nil
+}
+
+// Normalize は Parse で読めれば7桁の郵便番号を、読めなければ元の値から空白と〒を除いたものを返す。
+func Normalize(s string) string {
+	code, _, err := Parse(s)
+	if err != nil {
+		return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "〒"))
+	}
+	return code
+}
+
+// Valid は code が正規化済みの7桁の郵便番号かどうかを返す。
+func Valid(code string) bool {
+	if len(code) != 7 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Format は7桁の郵便番号を「123-4567」の形にする。7桁でなければそのまま返す。
+func Format(code string) string {
+	if Valid(code) {
+		return code[:3] + "-" + code[3:]
+	}
+	return code
+}
@@ -0,0 +1,51 @@
+package postal
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		code    string
+		fixed   bool
+		wantErr bool
+	}{
+		{"", "", false, false},
+		{"  ", "", false, false},
+		{"1000001", "1000001", false, false},
+		{"100-0001", "1000001", false, false},
+		{"〒100-0001", "1000001", false, false},
+		{"〒１００－０００１", "1000001", false, false},
+		{"100 0001", "1000001", false, false},
+		{"100ー0001", "1000001", false, false},
+		{"600001", "0600001", true, false},
+		{"600001.0", "0600001", true, false},
+		{"6.00001E+05", "0600001", true, false},
+		{"060-001", "060001", false, true},
+		{"12345678", "12345678", false, true},
+		{"100-000a", "100-000a", false, true},
+		{"1.5", "1.5", false, true},
+	}
+	for _, tt := range tests {
+		code, fixed, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if code != tt.code || fixed != tt.fixed {
+			t.Errorf("Parse(%q) = %q, %v, want %q, %v", tt.in, code, fixed, tt.code, tt.fixed)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"1000001", "100-0001"},
+		{"100001", "100001"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.in); got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
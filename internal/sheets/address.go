@@ -0,0 +1,106 @@
+package sheets
+
+import (
+	"fmt"
+	"strings"
+
+	"atena_printer/internal/journal"
+	"atena_printer/internal/model"
+	"atena_printer/internal/postal"
+)
+
+// FillAddresses は対象行の郵便番号・住所1・住所2の空のセルに値を書き込む。
+// 読み込み後にセルに値が入った場合は上書きを避けるため何も書き込まずにエラーにする。
+// 列が無い場合、ローカルTSVなど列を追加できるデータソースでは末尾に追加する。
+func (c *Client) FillAddresses(fills []model.AddressFill) error {
+	if !c.Writable() {
+		return fmt.Errorf("読み取り専用モードでは complete は使えません。credentials_file か oauth_client_file を設定したスプレッドシートモードか tsv_file を使用してください")
+	}
+
+	values, err := c.backend.getValues(c.sheetName + "!A1:ZZ")
+	if err != nil {
+		return fmt.Errorf("シートの読み込みに失敗: %w", err)
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("ヘッダ行が空です")
+	}
+
+	header := values[0]
+	l, err := newLayout(header, c.columns)
+	if err != nil {
+		return err
+	}
+
+	var changes []journal.Change
+	next := len(header)
+	column := func(col int, name string, needed bool) (int, error) {
+		if col >= 0 || !needed {
+			return col, nil
+		}
+		if !c.CanAddColumns() {
+			return -1, fmt.Errorf("列 '%s' が見つかりません。スプレッドシートで列を追加してください", name)
+		}
+		col, next = next, next+1
+		changes = append(changes, c.cellChange(values, l, 1, col, name, "", name))
+		return col, nil
+	}
+	var needPostal, needAddress1, needAddress2 bool
+	for _, f := range fills {
+		needPostal = needPostal || f.PostalCode != ""
+		needAddress1 = needAddress1 || f.Address1 != ""
+		needAddress2 = needAddress2 || f.Address2 != ""
+	}
+	postalCol, err := column(l.postal, l.cols.PostalCode, needPostal)
+	if err != nil {
+		return err
+	}
+	address1Col, err := column(l.address1, l.cols.Address1, needAddress1)
+	if err != nil {
+		return err
+	}
+	address2Col, err := column(l.address2, l.cols.Address2, needAddress2)
+	if err != nil {
+		return err
+	}
+
+	targets := make([]model.Address, len(fills))
+	for i, f := range fills {
+		targets[i] = f.Target
+	}
+	rows, err := resolveRows(values, l, targets)
+	if err != nil {
+		return err
+	}
+
+	headerOf := func(col int, name string) string {
+		return firstNonEmpty(getCell(header, col), name)
+	}
+	var conflicts []string
+	for i, f := range fills {
+		row := rows[i]
+		for _, cell := range []struct {
+			col    int
+			column string
+			value  string
+		}{
+			{postalCol, headerOf(postalCol, l.cols.PostalCode), postal.Format(f.PostalCode)},
+			{address1Col, headerOf(address1Col, l.cols.Address1), f.Address1},
+			{address2Col, headerOf(address2Col, l.cols.Address2), f.Address2},
+		} {
+			if cell.value == "" {
+				continue
+			}
+			ch := c.cellChange(values, l, row, cell.col, cell.column, "", cell.value)
+			if current := getCell(values[row-1], cell.col); current != "" {
+				conflicts = append(conflicts, fmt.Sprintf("  %s (%s %s): 空欄だったセルに %q が入っています", ch.Range, f.Target.FamilyName, f.Target.GivenName, current))
+				continue
+			}
+			changes = append(changes, ch)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("読み込み後に変更されたセルがあるため書き込みません。再実行してください:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return c.writeCells(changes, "")
+}
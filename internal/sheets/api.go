@@ -0,0 +1,230 @@
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/journal"
+)
+
+const defaultSheetsAPIBase = "https://sheets.googleapis.com/v4/spreadsheets"
+
+// tokenProvider は API の呼び出しに使うアクセストークンを発行する。
+type tokenProvider interface {
+	getToken() (string, error)
+	setTokenURI(uri string)
+}
+
+// apiBackend はサービスアカウントかログインした Google アカウントで Google Sheets API を読み書きする。
+type apiBackend struct {
+	ts            tokenProvider
+	http          *requester
+	base          string
+	spreadsheetID string
+	sheetName     string
+
+	subject      string // ドメイン全体の委任で代理するユーザー
+	quotaProject string // 利用上限・課金の対象にするプロジェクト (gcloud の quota_project_id)
+}
+
+func (b *apiBackend) requester() *requester {
+	return b.http
+}
+
+func (b *apiBackend) setEndpoints(e config.Endpoints) {
+	if e.SheetsAPI != "" {
+		b.base = strings.TrimRight(e.SheetsAPI, "/")
+	}
+	if e.Token != "" {
+		b.ts.setTokenURI(e.Token)
+	}
+}
+
+func (b *apiBackend) target() string {
+	return b.spreadsheetID + "/" + b.sheetName
+}
+
+func (b *apiBackend) describe() string {
+	var who string
+	switch b.http.cred {
+	case credServiceAccount:
+		who = "サービスアカウント"
+		if b.subject != "" {
+			who = "サービスアカウント, 代理: " + b.subject
+		}
+	case credLogin:
+		who = firstNonEmpty(b.http.account, "ログイン中のアカウント")
+	case credAuthorizedUser:
+		who = "gcloud のアカウント"
+	case credCommand:
+		who = "token_command"
+	}
+	return fmt.Sprintf("Google Sheets (%s) %s / %s", who, b.spreadsheetID, b.sheetName)
+}
+
+func (b *apiBackend) canAddColumns() bool {
+	return false
+}
+
+// call は認証付きで API を呼び出し、応答を out に読み込む (out が nil の場合は読み込まない)。
+func (b *apiBackend) call(op, method, u string, idempotent bool, body any, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	// 再試行の待ち時間の間にトークンが切れることがあるので、送るたびに取り直す (有効な間はキャッシュが返る)
+	resp, err := b.http.do(op, idempotent, func(ctx context.Context) (*http.Request, error) {
+		token, err := b.ts.getToken()
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if b.quotaProject != "" {
+			req.Header.Set("X-Goog-User-Project", b.quotaProject)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(resp, out); err != nil {
+			return fmt.Errorf("API 応答の解析に失敗: %w", err)
+		}
+	}
+	return nil
+}
+
+type valuesResponse struct {
+	Values [][]string `json:"values"`
+}
+
+func (b *apiBackend) getValues(readRange string) ([][]string, error) {
+	u := fmt.Sprintf("%s/%s/values/%s",
+		b.base,
+		b.spreadsheetID,
+		url.PathEscape(readRange))
+
+	var result valuesResponse
+	if err := b.call("シートの読み込み", "GET", u, true, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Values, nil
+}
+
+func (b *apiBackend) writeCells(changes []journal.Change) error {
+	var data []batchData
+	for _, ch := range changes {
+		data = append(data, batchData{
+			Range:  ch.Range,
+			Values: [][]string{{ch.New}},
+		})
+	}
+	return b.batchUpdate(data)
+}
+
+type batchData struct {
+	Range  string     `json:"range"`
+	Values [][]string `json:"values"`
+}
+
+type batchUpdateRequest struct {
+	ValueInputOption string      `json:"valueInputOption"`
+	Data             []batchData `json:"data"`
+}
+
+// batchUpdate はセルの値をまとめて書き込む。同じ値を書き込むだけなので再試行しても安全。
+func (b *apiBackend) batchUpdate(data []batchData) error {
+	u := fmt.Sprintf("%s/%s/values:batchUpdate", b.base, b.spreadsheetID)
+	return b.call("シートへの書き込み", "POST", u, true, batchUpdateRequest{
+		ValueInputOption: "USER_ENTERED",
+		Data:             data,
+	}, nil)
+}
+
+// sheetID はシート名に対応するシートID (spreadsheets:batchUpdate で使う) を返す。
+func (b *apiBackend) sheetID() (int64, error) {
+	u := fmt.Sprintf("%s/%s?fields=%s", b.base, b.spreadsheetID, url.QueryEscape("sheets.properties(sheetId,title)"))
+
+	var result struct {
+		Sheets []struct {
+			Properties struct {
+				SheetID int64  `json:"sheetId"`
+				Title   string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	if err := b.call("シート情報の読み込み", "GET", u, true, nil, &result); err != nil {
+		return 0, err
+	}
+	for _, s := range result.Sheets {
+		if s.Properties.Title == b.sheetName {
+			return s.Properties.SheetID, nil
+		}
+	}
+	return 0, fmt.Errorf("シート '%s' が見つかりません", b.sheetName)
+}
+
+// insertColumns は spreadsheets:batchUpdate で列の挿入・ヘッダの書き込み・入力規則と書式のコピーを
+// 1回のリクエストで行う (途中で失敗した場合はシートは変更されない)。
+func (b *apiBackend) insertColumns(at int, headers []string, formatFrom []int) error {
+	sheetID, err := b.sheetID()
+	if err != nil {
+		return err
+	}
+
+	var cells []map[string]any
+	for _, h := range headers {
+		cells = append(cells, map[string]any{"userEnteredValue": map[string]any{"stringValue": h}})
+	}
+	requests := []map[string]any{
+		{"insertDimension": map[string]any{
+			"range": map[string]any{
+				"sheetId":    sheetID,
+				"dimension":  "COLUMNS",
+				"startIndex": at,
+				"endIndex":   at + len(headers),
+			},
+			"inheritFromBefore": false,
+		}},
+		{"updateCells": map[string]any{
+			"start":  map[string]any{"sheetId": sheetID, "rowIndex": 0, "columnIndex": at},
+			"rows":   []map[string]any{{"values": cells}},
+			"fields": "userEnteredValue",
+		}},
+	}
+	for i, src := range formatFrom {
+		if src < 0 {
+			continue
+		}
+		for _, pasteType := range []string{"PASTE_DATA_VALIDATION", "PASTE_FORMAT"} {
+			requests = append(requests, map[string]any{"copyPaste": map[string]any{
+				"source":      map[string]any{"sheetId": sheetID, "startRowIndex": 1, "startColumnIndex": src, "endColumnIndex": src + 1},
+				"destination": map[string]any{"sheetId": sheetID, "startRowIndex": 1, "startColumnIndex": at + i, "endColumnIndex": at + i + 1},
+				"pasteType":   pasteType,
+			}})
+		}
+	}
+
+	// 列の挿入は繰り返すと二重に挿入されるため、429 以外では再試行しない
+	u := fmt.Sprintf("%s/%s:batchUpdate", b.base, b.spreadsheetID)
+	return b.call("列の追加", "POST", u, false, map[string]any{"requests": requests}, nil)
+}
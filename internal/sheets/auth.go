@@ -1,23 +1,22 @@
 package sheets
 
 import (
+	"context"
+	"crypto"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
 	"sync"
 	"time"
-
-	"crypto"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/base64"
 )
 
 type serviceAccountKey struct {
@@ -30,12 +29,14 @@ type serviceAccountKey struct {
 type tokenSource struct {
 	key      serviceAccountKey
 	privKey  *rsa.PrivateKey
+	http     *requester
+	subject  string // ドメイン全体の委任で代理するユーザー (空なら委任しない)
 	mu       sync.Mutex
 	token    string
 	expiry   time.Time
 }
 
-func newTokenSource(credentialsFile string) (*tokenSource, error) {
+func newTokenSource(credentialsFile string, r *requester) (*tokenSource, error) {
 	data, err := os.ReadFile(credentialsFile)
 	if err != nil {
 		return nil, fmt.Errorf("認証ファイルの読み込みに失敗: %w", err)
@@ -47,7 +48,7 @@ func newTokenSource(credentialsFile string) (*tokenSource, error) {
 	}
 
 	if key.TokenURI == "" {
-		key.TokenURI = "https://oauth2.googleapis.com/token"
+		key.TokenURI = defaultTokenURI
 	}
 
 	block, _ := pem.Decode([]byte(key.PrivateKey))
@@ -55,17 +56,34 @@ func newTokenSource(credentialsFile string) (*tokenSource, error) {
 		return nil, fmt.Errorf("秘密鍵のデコードに失敗")
 	}
 
-	privKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("秘密鍵の解析に失敗: %w", err)
-	}
-
-	rsaKey, ok := privKey.(*rsa.PrivateKey)
-	if !ok {
-		return nil, fmt.Errorf("RSA秘密鍵ではありません")
-	}
+	// Google が発行する鍵は PKCS#8 ("PRIVATE KEY")。openssl などで作り直した PKCS#1 ("RSA PRIVATE KEY") も受け付ける
+	var rsaKey *rsa.PrivateKey
+	if block.Type == "RSA PRIVATE KEY" {
+		rsaKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("秘密鍵の解析に失敗: %w", err)
+		}
+	} else {
+		privKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			if k, err1 := x509.ParsePKCS1PrivateKey(block.Bytes); err1 == nil {
+				privKey = k
+			} else {
+				return nil, fmt.Errorf("秘密鍵の解析に失敗: %w", err)
+			}
+		}
+		var ok bool
+		rsaKey, ok = privKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("RSA秘密鍵ではありません")
+		}
+	}
+
+	return &tokenSource{key: key, privKey: rsaKey, http: r}, nil
+}
 
-	return &tokenSource{key: key, privKey: rsaKey}, nil
+func (ts *tokenSource) setTokenURI(uri string) {
+	ts.key.TokenURI = uri
 }
 
 func (ts *tokenSource) getToken() (string, error) {
@@ -79,33 +97,34 @@ func (ts *tokenSource) getToken() (string, error) {
 	now := time.Now()
 	claims := map[string]interface{}{
 		"iss":   ts.key.ClientEmail,
-		"scope": "https://www.googleapis.com/auth/spreadsheets",
+		"scope": sheetsScope,
 		"aud":   ts.key.TokenURI,
 		"iat":   now.Unix(),
 		"exp":   now.Add(time.Hour).Unix(),
 	}
+	if ts.subject != "" {
+		claims["sub"] = ts.subject
+	}
 
 	jwt, err := signJWT(claims, ts.privKey)
 	if err != nil {
 		return "", fmt.Errorf("JWT署名に失敗: %w", err)
 	}
 
-	resp, err := http.PostForm(ts.key.TokenURI, url.Values{
+	form := url.Values{
 		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
 		"assertion":  {jwt},
+	}.Encode()
+	body, err := ts.http.do("トークン取得", true, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", ts.key.TokenURI, strings.NewReader(form))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
 	})
 	if err != nil {
-		return "", fmt.Errorf("トークン取得に失敗: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("トークン応答の読み込みに失敗: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("トークン取得失敗 (HTTP %d): %s", resp.StatusCode, string(body))
+		return "", err
 	}
 
 	var tokenResp struct {
@@ -1,81 +1,119 @@
 package sheets
 
 import (
-	"bytes"
-	"encoding/csv"
-	"encoding/json"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"net/http"
-	"net/url"
-	"os"
+	"strconv"
 	"strings"
 
+	"atena_printer/internal/config"
+	"atena_printer/internal/journal"
 	"atena_printer/internal/model"
+	"atena_printer/internal/postal"
 )
 
-const sheetsAPIBase = "https://sheets.googleapis.com/v4/spreadsheets"
-
-type clientMode int
+// backend はシートの値の読み込み元 (Sheets API・公開CSV・ローカルTSV)。
+type backend interface {
+	getValues(readRange string) ([][]string, error)
+	// target は書き込み記録に残す書き込み先の識別子。
+	target() string
+	describe() string
+}
 
-const (
-	modeServiceAccount clientMode = iota
-	modePublicCSV
-	modeTSV
-)
+// cellWriter はセルの書き込みに対応した backend。
+type cellWriter interface {
+	writeCells(changes []journal.Change) error
+	// canAddColumns は存在しない列をヘッダの末尾に追加して書き込めるかどうか。
+	canAddColumns() bool
+}
 
+// Client は backend から読み込んだ値を住所録として扱う。
 type Client struct {
-	mode          clientMode
-	ts            *tokenSource
-	httpClient    *http.Client
-	spreadsheetID string
-	sheetName     string
-	publicCSVURL  string
-	tsvFile       string
+	backend   backend
+	sheetName string
+
+	journal *journal.Journal
+	command string
+
+	columns      config.Columns
+	statusValues config.StatusValues
+	warn         io.Writer
 }
 
-func New(credentialsFile, spreadsheetID, sheetName, tsvFile string) (*Client, error) {
-	if tsvFile != "" {
-		return &Client{
-			mode:       modeTSV,
-			tsvFile:    tsvFile,
-			sheetName:  sheetName,
-			httpClient: &http.Client{},
-		}, nil
-	}
-
-	if credentialsFile == "" {
-		publicCSVURL := fmt.Sprintf(
-			"https://docs.google.com/spreadsheets/d/%s/gviz/tq?tqx=out:csv&sheet=%s",
-			url.PathEscape(spreadsheetID),
-			url.QueryEscape(sheetName),
-		)
-		return &Client{
-			mode:          modePublicCSV,
-			httpClient:    &http.Client{},
-			spreadsheetID: spreadsheetID,
-			sheetName:     sheetName,
-			publicCSVURL:  publicCSVURL,
-		}, nil
-	}
-
-	ts, err := newTokenSource(credentialsFile)
-	if err != nil {
-		return nil, err
-	}
-	return &Client{
-		mode:          modeServiceAccount,
-		ts:            ts,
-		httpClient:    &http.Client{},
-		spreadsheetID: spreadsheetID,
-		sheetName:     sheetName,
-	}, nil
+// SetStatusValues は年ステータス列の値の解釈を設定する (未設定の場合は既定値)。
+func (c *Client) SetStatusValues(v config.StatusValues) {
+	c.statusValues = v
+}
+
+// SetWarnings は読み込み時の警告 (判定できない値など) の出力先を設定する。nil の場合は出力しない。
+// API の再試行の案内もここに出力する。
+func (c *Client) SetWarnings(w io.Writer) {
+	c.warn = w
+	if b, ok := c.backend.(httpBackend); ok {
+		b.requester().warn = w
+	}
+}
+
+// endpointSetter は API の URL を変更できる backend。
+type endpointSetter interface {
+	setEndpoints(e config.Endpoints)
+}
+
+// SetEndpoints は Google の各 API の URL を変更する (空の項目は変更しない)。
+func (c *Client) SetEndpoints(e config.Endpoints) {
+	if b, ok := c.backend.(endpointSetter); ok {
+		b.setEndpoints(e)
+	}
+}
+
+// SetColumns はシートの列と項目の対応を設定する (未設定の項目は既定の列名)。
+func (c *Client) SetColumns(cols config.Columns) {
+	c.columns = cols
+}
+
+// Writable は書き込みに対応したデータソースかどうかを返す。
+func (c *Client) Writable() bool {
+	_, ok := c.backend.(cellWriter)
+	return ok
+}
+
+// CanAddColumns は書き込み時に足りない列を追加できるかどうかを返す。
+func (c *Client) CanAddColumns() bool {
+	w, ok := c.backend.(cellWriter)
+	return ok && w.canAddColumns()
+}
+
+// Describe はデータソースの説明を返す。
+func (c *Client) Describe() string {
+	return c.backend.describe()
 }
 
-// ReadAddresses は設定されたデータソースから住所一覧を読み込む。
+// SetJournal は以降の書き込みを command の操作として journal に記録するよう設定する。
+func (c *Client) SetJournal(j *journal.Journal, command string) {
+	c.journal = j
+	c.command = command
+}
+
+// ReadAddresses は設定されたデータソースから住所一覧と指定年のステータスを読み込む。
 func (c *Client) ReadAddresses(year int) ([]model.Address, map[int]model.YearStatus, error) {
+	addresses, histories, err := c.ReadHistory()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statuses := make(map[int]model.YearStatus, len(addresses))
+	for _, addr := range addresses {
+		statuses[addr.Row] = histories[addr.Row][year]
+	}
+	return addresses, statuses, nil
+}
+
+// ReadHistory は住所一覧と、ヘッダにあるすべての年ステータス列 (既定では「YYYY送/受/喪中」) のステータスを行番号ごとに読み込む。
+func (c *Client) ReadHistory() ([]model.Address, map[int]model.History, error) {
 	readRange := c.sheetName + "!A1:ZZ"
-	values, err := c.getValues(readRange)
+	values, err := c.backend.getValues(readRange)
 	if err != nil {
 		return nil, nil, fmt.Errorf("住所データの読み込みに失敗: %w", err)
 	}
@@ -84,31 +122,52 @@ func (c *Client) ReadAddresses(year int) ([]model.Address, map[int]model.YearSta
 		return nil, nil, fmt.Errorf("住所データがありません")
 	}
 
+	l, err := newLayout(values[0], c.columns)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	header := values[0]
-	colIdx := buildColumnIndex(header)
-	yearCols := findYearColumns(header, year)
+	sv := newStatusValues(c.statusValues)
+	checked := func(row []string, col, rowNum int) bool {
+		if col < 0 {
+			return false
+		}
+		return sv.checked(getCell(row, col), getCell(header, col), rowNum)
+	}
 
 	var addresses []model.Address
-	statuses := make(map[int]model.YearStatus)
+	var postalWarnings []string
+	histories := make(map[int]model.History)
 
 	for i, row := range values[1:] {
 		rowNum := i + 2 // 1-indexed, skip header
 
-		familyName := getCell(row, colIdx["姓"])
+		familyName, givenName := l.name(row)
 		if familyName == "" {
 			continue
 		}
 
-		postalCode := normalizePostalCode(getCell(row, colIdx["郵便番号"]))
+		postalCode, fixed, err := postal.Parse(getCell(row, l.postal))
+		if fixed {
+			postalWarnings = append(postalWarnings, fmt.Sprintf(
+				"%d行目 (%s %s): 郵便番号 %s は先頭の0が欠けているとみなし %s として扱います。セルの書式を「書式なしテキスト」にして入力し直してください",
+				rowNum, familyName, givenName, getCell(row, l.postal), postal.Format(postalCode)))
+		} else if err != nil {
+			postalWarnings = append(postalWarnings, fmt.Sprintf("%d行目 (%s %s): %v", rowNum, familyName, givenName, err))
+		}
 
 		addr := model.Address{
+			ID:         getCell(row, l.id),
 			FamilyName: familyName,
-			GivenName:  getCell(row, colIdx["名"]),
-			JointNames: parseJointNames(getCell(row, colIdx["連名"])),
-			Honorific:  getCell(row, colIdx["敬称"]),
+			GivenName:  givenName,
+			Reading:    getCell(row, l.reading),
+			JointNames: model.SplitList(getCell(row, l.joint)),
+			Honorific:  getCell(row, l.honorific),
 			PostalCode: postalCode,
-			Address1:   getCell(row, colIdx["住所1"]),
-			Address2:   getCell(row, colIdx["住所2"]),
+			Address1:   getCell(row, l.address1),
+			Address2:   getCell(row, l.address2),
+			Tags:       model.SplitList(getCell(row, l.tags)),
 			Row:        rowNum,
 		}
 		if addr.Honorific == "" {
@@ -117,234 +176,362 @@ func (c *Client) ReadAddresses(year int) ([]model.Address, map[int]model.YearSta
 
 		addresses = append(addresses, addr)
 
-		status := model.YearStatus{
-			Sent:     isChecked(getCell(row, yearCols.sent)),
-			Received: isChecked(getCell(row, yearCols.received)),
-			Mourning: isChecked(getCell(row, yearCols.mourning)),
+		history := make(model.History, len(l.years))
+		for year, yc := range l.years {
+			history[year] = model.YearStatus{
+				Sent:     checked(row, yc.sent, rowNum),
+				Received: checked(row, yc.received, rowNum),
+				Mourning: checked(row, yc.mourning, rowNum),
+			}
 		}
-		statuses[rowNum] = status
+		histories[rowNum] = history
 	}
 
-	return addresses, statuses, nil
+	if c.warn != nil {
+		for _, msg := range append(sv.warnings(), postalWarnings...) {
+			fmt.Fprintf(c.warn, "警告: %s\n", msg)
+		}
+	}
+
+	return addresses, histories, nil
+}
+
+// MarkSent はスプレッドシートの対象行の「YYYY送」列 (columns.sent) に ○ などを書き込む。
+// 書き込む値は列の内容に合わせる (チェックボックスの列なら TRUE)。
+// 対象の行は書き込み直前に読み直したシートから ID (無ければ行番号と氏名) で特定する。
+// 列が無い場合、ローカルTSVなど列を追加できるデータソースでは末尾に追加する。
+func (c *Client) MarkSent(year int, targets []model.Address) error {
+	return c.markYearColumn(kindSent, year, "mark-sent", targets)
+}
+
+// MarkReceived はスプレッドシートの対象行の「YYYY受」列 (columns.received) に ○ などを書き込む。
+func (c *Client) MarkReceived(year int, targets []model.Address) error {
+	return c.markYearColumn(kindReceived, year, "mark-received", targets)
 }
 
-// MarkSent はスプレッドシートの指定行の「YYYY送」列に ○ を書き込む。
-func (c *Client) MarkSent(year int, rows []int) error {
-	if c.mode != modeServiceAccount {
-		return fmt.Errorf("読み取り専用モードでは mark-sent は使えません。credentials_file を設定したスプレッドシートモードを使用してください")
+func (c *Client) markYearColumn(kind string, year int, command string, targets []model.Address) error {
+	if !c.Writable() {
+		return fmt.Errorf("読み取り専用モードでは %s は使えません。credentials_file か oauth_client_file を設定したスプレッドシートモードか tsv_file を使用してください", command)
 	}
 
-	headerValues, err := c.getValues(c.sheetName + "!1:1")
+	values, err := c.backend.getValues(c.sheetName + "!A1:ZZ")
 	if err != nil {
-		return fmt.Errorf("ヘッダ行の読み込みに失敗: %w", err)
+		return fmt.Errorf("シートの読み込みに失敗: %w", err)
 	}
-	if len(headerValues) == 0 {
+	if len(values) == 0 {
 		return fmt.Errorf("ヘッダ行が空です")
 	}
 
-	header := headerValues[0]
-	sentColName := fmt.Sprintf("%d送", year)
-	sentColIdx := -1
-	for i, h := range header {
-		if h == sentColName {
-			sentColIdx = i
-			break
+	header := values[0]
+	l, err := newLayout(header, c.columns)
+	if err != nil {
+		return err
+	}
+	colName := l.yearHeader(kind, year)
+	colIdx := l.yearColumn(kind, year)
+
+	var changes []journal.Change
+	if colIdx < 0 {
+		if !c.CanAddColumns() {
+			return fmt.Errorf("列 '%s' が見つかりません。new-year コマンドかスプレッドシートで列を追加してください", colName)
 		}
+		// ローカルTSVなどでは末尾に列を追加する
+		colIdx = len(header)
+		changes = append(changes, c.cellChange(values, l, 1, colIdx, colName, "", colName))
 	}
-	if sentColIdx < 0 {
-		return fmt.Errorf("列 '%s' が見つかりません。スプレッドシートに列を追加してください", sentColName)
+
+	rows, err := resolveRows(values, l, targets)
+	if err != nil {
+		return err
 	}
 
-	colLetter := columnLetter(sentColIdx)
+	// 同じ種類の他の年の列 (書き込む値の参考にする)
+	var others []int
+	for y := range l.years {
+		if col := l.yearColumn(kind, y); y != year && col >= 0 {
+			others = append(others, col)
+		}
+	}
+	mark := newStatusValues(c.statusValues).writeValue(values, colIdx, others)
 
-	var data []batchData
 	for _, row := range rows {
-		cellRange := fmt.Sprintf("%s!%s%d", c.sheetName, colLetter, row)
-		data = append(data, batchData{
-			Range:  cellRange,
-			Values: [][]string{{"○"}},
-		})
+		changes = append(changes, c.cellChange(values, l, row, colIdx, colName, getCell(values[row-1], colIdx), mark))
 	}
 
-	return c.batchUpdate(data)
+	return c.writeCells(changes, "")
 }
 
-// --- Sheets API raw HTTP ---
-
-type valuesResponse struct {
-	Values [][]string `json:"values"`
-}
-
-func (c *Client) getValues(readRange string) ([][]string, error) {
-	if c.mode == modePublicCSV {
-		return c.getValuesFromPublicCSV(readRange)
-	}
-	if c.mode == modeTSV {
-		return c.getValuesFromTSV(readRange)
+// AssignIDs は ID 列が空の行に新しい ID を書き込み、割り当てた件数を返す。
+// ID 列が無い場合はヘッダ行の末尾に追加する。dryRun の場合は書き込まない。
+func (c *Client) AssignIDs(dryRun bool) (int, error) {
+	if !c.Writable() {
+		return 0, fmt.Errorf("読み取り専用モードでは assign-ids は使えません。credentials_file か oauth_client_file を設定したスプレッドシートモードか tsv_file を使用してください")
 	}
 
-	token, err := c.ts.getToken()
+	values, err := c.backend.getValues(c.sheetName + "!A1:ZZ")
 	if err != nil {
-		return nil, err
+		return 0, fmt.Errorf("シートの読み込みに失敗: %w", err)
 	}
-
-	u := fmt.Sprintf("%s/%s/values/%s",
-		sheetsAPIBase,
-		c.spreadsheetID,
-		url.PathEscape(readRange))
-
-	req, err := http.NewRequest("GET", u, nil)
-	if err != nil {
-		return nil, err
+	if len(values) == 0 {
+		return 0, fmt.Errorf("ヘッダ行が空です")
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err := c.httpClient.Do(req)
+	header := values[0]
+	l, err := newLayout(header, c.columns)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var changes []journal.Change
+	idCol := l.id
+	if idCol < 0 {
+		idCol = len(header)
+		changes = append(changes, c.cellChange(values, l, 1, idCol, l.cols.ID, "", l.cols.ID))
 	}
+	idHeader := firstNonEmpty(getCell(header, idCol), l.cols.ID)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API エラー (HTTP %d): %s", resp.StatusCode, string(body))
+	used := make(map[string]bool)
+	for _, row := range values[1:] {
+		if id := getCell(row, idCol); id != "" {
+			used[id] = true
+		}
 	}
 
-	var result valuesResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("API 応答の解析に失敗: %w", err)
+	assigned := 0
+	for i, row := range values[1:] {
+		if family, _ := l.name(row); family == "" || getCell(row, idCol) != "" {
+			continue
+		}
+		id, err := newID(used)
+		if err != nil {
+			return 0, err
+		}
+		ch := c.cellChange(values, l, i+2, idCol, idHeader, "", id)
+		// 取り消す時は書き込んだ ID で行を探す
+		ch.ID, ch.Name = id, ""
+		changes = append(changes, ch)
+		assigned++
 	}
 
-	return result.Values, nil
+	if dryRun || assigned == 0 {
+		return assigned, nil
+	}
+	return assigned, c.writeCells(changes, "")
 }
 
-func (c *Client) getValuesFromPublicCSV(readRange string) ([][]string, error) {
-	req, err := http.NewRequest("GET", c.publicCSVURL, nil)
-	if err != nil {
-		return nil, err
+// Undo は記録された操作で書き込んだセルを元の値に戻す。
+// セルは記録した行の ID (無ければ氏名) と列のヘッダで探し直すので、書き込み後に行の挿入や並べ替えがあっても同じセルを戻せる。
+// セルが見つからない場合や値が書き込んだ時から変わっている場合は何も書き込まずにエラーにする。
+func (c *Client) Undo(op *journal.Operation) error {
+	if !c.Writable() {
+		return fmt.Errorf("読み取り専用モードでは undo は使えません。credentials_file か oauth_client_file を設定したスプレッドシートモードか tsv_file を使用してください")
 	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	if op.Target != c.backend.target() {
+		return fmt.Errorf("操作 %s の書き込み先 (%s) が現在の設定 (%s) と異なります", op.ID, op.Target, c.backend.target())
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	values, err := c.backend.getValues(c.sheetName + "!A1:ZZ")
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("シートの読み込みに失敗: %w", err)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(
-			"公開シートの読み込みに失敗 (HTTP %d)。シートを『リンクを知っている全員が閲覧可』にしているか確認してください",
-			resp.StatusCode,
-		)
+	if len(values) == 0 {
+		return fmt.Errorf("ヘッダ行が空です")
 	}
-
-	reader := csv.NewReader(bytes.NewReader(body))
-	reader.FieldsPerRecord = -1
-	values, err := reader.ReadAll()
+	l, err := newLayout(values[0], c.columns)
 	if err != nil {
-		return nil, fmt.Errorf("公開CSVの解析に失敗: %w", err)
+		return err
 	}
 
-	if strings.HasSuffix(readRange, "!1:1") {
-		if len(values) == 0 {
-			return nil, nil
+	var changes []journal.Change
+	var conflicts []string
+	for _, ch := range op.Changes {
+		row, col, err := c.locate(values, l, ch)
+		if err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("  %s: %v", ch.Range, err))
+			continue
+		}
+		var current string
+		if row-1 < len(values) {
+			current = getCell(values[row-1], col)
 		}
-		return [][]string{values[0]}, nil
+		r := fmt.Sprintf("%s!%s%d", c.sheetName, columnLetter(col), row)
+		if current != ch.New {
+			conflicts = append(conflicts, fmt.Sprintf("  %s: 書き込んだ値 %q が現在は %q です", r, ch.New, current))
+			continue
+		}
+		changes = append(changes, journal.Change{Range: r, ID: ch.ID, Name: ch.Name, Column: ch.Column, Old: ch.New, New: ch.Old})
 	}
 
-	return values, nil
-}
-
-func (c *Client) getValuesFromTSV(readRange string) ([][]string, error) {
-	data, err := os.ReadFile(c.tsvFile)
-	if err != nil {
-		return nil, fmt.Errorf("TSVファイルの読み込みに失敗: %w", err)
+	if len(conflicts) > 0 {
+		return fmt.Errorf("書き込み後に変更されたセルがあるため取り消せません:\n%s", strings.Join(conflicts, "\n"))
 	}
+	return c.writeCells(changes, op.ID)
+}
 
-	// UTF-8 BOM が付いている場合に先頭セルへ混入しないよう除去する。
-	if len(data) >= 3 && bytes.Equal(data[:3], []byte{0xEF, 0xBB, 0xBF}) {
-		data = data[3:]
+// cellChange は row 行目 (1-indexed) の列 col のセルの書き込みを作る。
+// 取り消す時に探し直せるよう、行の ID (無ければ氏名) と列のヘッダ column を記録する。
+func (c *Client) cellChange(values [][]string, l *layout, row, col int, column, old, new string) journal.Change {
+	ch := journal.Change{
+		Range:  fmt.Sprintf("%s!%s%d", c.sheetName, columnLetter(col), row),
+		Column: column,
+		Old:    old,
+		New:    new,
+	}
+	if row > 1 && row-1 < len(values) {
+		if ch.ID = getCell(values[row-1], l.id); ch.ID == "" {
+			family, given := l.name(values[row-1])
+			ch.Name = family + " " + given
+		}
 	}
+	return ch
+}
 
-	reader := csv.NewReader(bytes.NewReader(data))
-	reader.Comma = '\t'
-	reader.FieldsPerRecord = -1
-	values, err := reader.ReadAll()
+// locate は書き込み記録のセルの現在の位置 (行は 1-indexed、列は 0-indexed) を返す。
+// 行は ID で探し、ID の無い行は記録した行番号の氏名が変わっていないことを確かめる。列はヘッダで探す。
+// ID・ヘッダの無い古い記録は Range の位置のまま。
+func (c *Client) locate(values [][]string, l *layout, ch journal.Change) (row, col int, err error) {
+	row, col, err = parseCellRange(ch.Range)
 	if err != nil {
-		return nil, fmt.Errorf("TSVの解析に失敗: %w", err)
+		return 0, 0, err
 	}
 
-	if strings.HasSuffix(readRange, "!1:1") {
-		if len(values) == 0 {
-			return nil, nil
+	if ch.Column != "" {
+		i, ok := buildColumnIndex(values[0])[ch.Column]
+		if !ok {
+			return 0, 0, fmt.Errorf("列 '%s' が見つかりません", ch.Column)
 		}
-		return [][]string{values[0]}, nil
+		col = i
 	}
 
-	return values, nil
-}
-
-type batchData struct {
-	Range  string     `json:"range"`
-	Values [][]string `json:"values"`
-}
-
-type batchUpdateRequest struct {
-	ValueInputOption string      `json:"valueInputOption"`
-	Data             []batchData `json:"data"`
+	switch {
+	case ch.ID != "":
+		if l.id < 0 {
+			return 0, 0, fmt.Errorf("ID 列が見つかりません")
+		}
+		var found []int
+		for i, r := range values[1:] {
+			if getCell(r, l.id) == ch.ID {
+				found = append(found, i+2)
+			}
+		}
+		switch len(found) {
+		case 0:
+			return 0, 0, fmt.Errorf("ID %s の行が見つかりません", ch.ID)
+		case 1:
+			row = found[0]
+		default:
+			return 0, 0, fmt.Errorf("ID %s の行が複数あります %v", ch.ID, found)
+		}
+	case ch.Name != "":
+		var family, given string
+		if row-1 < len(values) {
+			family, given = l.name(values[row-1])
+		}
+		if family+" "+given != ch.Name {
+			return 0, 0, fmt.Errorf("%d行目が %s の行ではなくなっています (行が移動した場合は ID 列を追加してから書き込むと取り消せます)", row, ch.Name)
+		}
+	}
+	return row, col, nil
 }
 
-func (c *Client) batchUpdate(data []batchData) error {
-	if c.mode != modeServiceAccount {
+// writeCells はセルを書き換える。journal が設定されていれば、書き込みの前に操作を記録する
+// (記録の後で書き込みに失敗した場合は、その操作を取り消し済みとして記録する)。
+// undoes には取り消す操作のIDを指定する (通常の書き込みでは空)。
+func (c *Client) writeCells(changes []journal.Change, undoes string) error {
+	w, ok := c.backend.(cellWriter)
+	if !ok {
 		return fmt.Errorf("読み取り専用モードでは書き込みできません")
 	}
 
-	token, err := c.ts.getToken()
-	if err != nil {
-		return err
+	var op *journal.Operation
+	if c.journal != nil {
+		op = &journal.Operation{
+			Command: c.command,
+			Target:  c.backend.target(),
+			Undoes:  undoes,
+			Changes: changes,
+		}
+		if err := c.journal.Append(op); err != nil {
+			return fmt.Errorf("書き込み記録の保存に失敗したため書き込みません: %w", err)
+		}
 	}
 
-	u := fmt.Sprintf("%s/%s/values:batchUpdate", sheetsAPIBase, c.spreadsheetID)
-
-	reqBody := batchUpdateRequest{
-		ValueInputOption: "USER_ENTERED",
-		Data:             data,
-	}
-	bodyBytes, err := json.Marshal(reqBody)
-	if err != nil {
+	if err := w.writeCells(changes); err != nil {
+		if op != nil {
+			if aerr := c.journal.Abort(op); aerr != nil {
+				return fmt.Errorf("%w (書き込み記録 %s を取り消し済みにできませんでした: %v)", err, op.ID, aerr)
+			}
+		}
 		return err
 	}
+	return nil
+}
 
-	req, err := http.NewRequest("POST", u, strings.NewReader(string(bodyBytes)))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
+// --- helpers ---
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
+// resolveRows は書き込み対象の宛先を現在のシート上の行番号に解決する。
+// ID がある宛先は ID で探し、無い宛先は読み込み時の行番号の氏名が一致することを確認する。
+// 見つからない・複数見つかる場合は誤った行への書き込みを避けるためエラーにする。
+func resolveRows(values [][]string, l *layout, targets []model.Address) ([]int, error) {
+	idRows := make(map[string][]int)
+	if l.id >= 0 {
+		for i, row := range values[1:] {
+			if id := getCell(row, l.id); id != "" {
+				idRows[id] = append(idRows[id], i+2)
+			}
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API エラー (HTTP %d): %s", resp.StatusCode, string(respBody))
+	var rows []int
+	var problems []string
+	for _, t := range targets {
+		name := t.FamilyName + " " + t.GivenName
+		if t.ID != "" {
+			found := idRows[t.ID]
+			switch len(found) {
+			case 1:
+				rows = append(rows, found[0])
+			case 0:
+				problems = append(problems, fmt.Sprintf("  %s (ID %s): 行が見つかりません", name, t.ID))
+			default:
+				problems = append(problems, fmt.Sprintf("  %s (ID %s): 同じ ID の行が複数あります %v", name, t.ID, found))
+			}
+			continue
+		}
+
+		idx := t.Row - 1
+		var family, given string
+		if idx >= 1 && idx < len(values) {
+			family, given = l.name(values[idx])
+		}
+		if family != t.FamilyName || given != t.GivenName {
+			problems = append(problems, fmt.Sprintf("  %s (%d行目): 行の内容が変わっています", name, t.Row))
+			continue
+		}
+		rows = append(rows, t.Row)
 	}
 
-	return nil
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("書き込み先の行を特定できません。シートを確認してから再実行してください:\n%s",
+			strings.Join(problems, "\n"))
+	}
+	return rows, nil
 }
 
-// --- helpers ---
+// newID は used に含まれない新しい ID を生成して used に登録する。
+func newID(used map[string]bool) (string, error) {
+	for {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("ID の生成に失敗: %w", err)
+		}
+		id := hex.EncodeToString(b)
+		if !used[id] {
+			used[id] = true
+			return id, nil
+		}
+	}
+}
 
 type yearColumns struct {
 	sent     int
@@ -352,32 +539,17 @@ type yearColumns struct {
 	mourning int
 }
 
-func buildColumnIndex(header []string) map[string]int {
-	idx := make(map[string]int)
+// columnIndex はヘッダ名から列位置 (0-indexed) を引く。
+type columnIndex map[string]int
+
+func buildColumnIndex(header []string) columnIndex {
+	idx := make(columnIndex)
 	for i, h := range header {
 		idx[h] = i
 	}
 	return idx
 }
 
-func findYearColumns(header []string, year int) yearColumns {
-	yc := yearColumns{sent: -1, received: -1, mourning: -1}
-	sentName := fmt.Sprintf("%d送", year)
-	recvName := fmt.Sprintf("%d受", year)
-	mournName := fmt.Sprintf("%d喪中", year)
-	for i, h := range header {
-		switch h {
-		case sentName:
-			yc.sent = i
-		case recvName:
-			yc.received = i
-		case mournName:
-			yc.mourning = i
-		}
-	}
-	return yc
-}
-
 func getCell(cells []string, idx int) string {
 	if idx < 0 || idx >= len(cells) {
 		return ""
@@ -385,33 +557,24 @@ func getCell(cells []string, idx int) string {
 	return strings.TrimSpace(cells[idx])
 }
 
-func isChecked(val string) bool {
-	return val != ""
-}
-
-func normalizePostalCode(code string) string {
-	code = strings.ReplaceAll(code, "-", "")
-	code = strings.ReplaceAll(code, "ー", "")
-	code = strings.ReplaceAll(code, "−", "")
-	code = strings.ReplaceAll(code, " ", "")
-	return code
-}
+// parseCellRange は「シート名!E12」形式の範囲から行番号 (1-indexed) と列位置 (0-indexed) を返す。
+func parseCellRange(r string) (row, col int, err error) {
+	cell := r
+	if i := strings.LastIndex(r, "!"); i >= 0 {
+		cell = r[i+1:]
+	}
 
-func parseJointNames(s string) []string {
-	if s == "" {
-		return nil
+	i := 0
+	col = 0
+	for i < len(cell) && cell[i] >= 'A' && cell[i] <= 'Z' {
+		col = col*26 + int(cell[i]-'A'+1)
+		i++
 	}
-	s = strings.ReplaceAll(s, "、", ",")
-	s = strings.ReplaceAll(s, "\n", ",")
-	parts := strings.Split(s, ",")
-	var names []string
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p != "" {
-			names = append(names, p)
-		}
+	row, convErr := strconv.Atoi(cell[i:])
+	if i == 0 || convErr != nil || row < 1 {
+		return 0, 0, fmt.Errorf("セル範囲を解析できません: %s", r)
 	}
-	return names
+	return row, col - 1, nil
 }
 
 func columnLetter(idx int) string {
@@ -0,0 +1,204 @@
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenCommandTimeout は token_command の実行を待つ時間。
+const tokenCommandTimeout = time.Minute
+
+// commandTokenLifetime は有効期限の分からないトークン (token_command がトークンだけを出力した場合) を使い回す時間。
+const commandTokenLifetime = 5 * time.Minute
+
+// Credentials は Sheets API の認証方法。Command → File → ApplicationDefault の順に優先する。
+type Credentials struct {
+	Command            string // アクセストークンを出力するコマンド (token_command)
+	File               string // サービスアカウントの鍵か gcloud の authorized_user のファイル (credentials_file)
+	ApplicationDefault bool   // アプリケーションのデフォルト認証情報 (ADC) を探す
+	Subject            string // ドメイン全体の委任で代理するユーザー (impersonate_user)
+}
+
+// NewAPI は cred の認証方法で Sheets API を読み書きする Client を作る。
+func NewAPI(cred Credentials, spreadsheetID, sheetName string) (*Client, error) {
+	r := newRequester()
+	b := &apiBackend{
+		http:          r,
+		base:          defaultSheetsAPIBase,
+		spreadsheetID: spreadsheetID,
+		sheetName:     sheetName,
+	}
+
+	path := cred.File
+	switch {
+	case cred.Command != "":
+		if cred.Subject != "" {
+			return nil, fmt.Errorf("impersonate_user は token_command と同時には使えません (コマンド側で代理するユーザーのトークンを出力してください)")
+		}
+		b.ts = &commandTokenSource{command: cred.Command}
+		r.cred = credCommand
+		return &Client{backend: b, sheetName: sheetName}, nil
+	case path == "" && cred.ApplicationDefault:
+		var err error
+		if path, err = FindDefaultCredentials(); err != nil {
+			return nil, err
+		}
+	case path == "":
+		return nil, fmt.Errorf("認証情報が設定されていません")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("認証ファイルの読み込みに失敗: %w", err)
+	}
+	var file struct {
+		Type           string `json:"type"`
+		ClientID       string `json:"client_id"`
+		ClientSecret   string `json:"client_secret"`
+		RefreshToken   string `json:"refresh_token"`
+		TokenURI       string `json:"token_uri"`
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("認証ファイルの解析に失敗 (%s): %w", path, err)
+	}
+
+	switch file.Type {
+	case "service_account", "":
+		ts, err := newTokenSource(path, r)
+		if err != nil {
+			return nil, err
+		}
+		ts.subject = cred.Subject
+		b.ts = ts
+		b.subject = cred.Subject
+		r.account = firstNonEmpty(cred.Subject, ts.key.ClientEmail)
+		r.cred = credServiceAccount
+	case "authorized_user":
+		if cred.Subject != "" {
+			return nil, fmt.Errorf("impersonate_user はサービスアカウントの鍵でのみ使えます (%s はユーザーの認証情報です)", path)
+		}
+		if file.RefreshToken == "" {
+			return nil, fmt.Errorf("認証ファイルに refresh_token がありません (%s)", path)
+		}
+		b.ts = &oauthTokenSource{
+			client: oauthClient{
+				ClientID:     file.ClientID,
+				ClientSecret: file.ClientSecret,
+				TokenURI:     firstNonEmpty(file.TokenURI, defaultTokenURI),
+			},
+			refresh: file.RefreshToken,
+			http:    r,
+		}
+		b.quotaProject = file.QuotaProjectID
+		r.cred = credAuthorizedUser
+	case "installed", "web":
+		return nil, fmt.Errorf("%s は OAuth クライアントのファイルです。oauth_client_file に指定して login を使ってください", path)
+	default:
+		return nil, fmt.Errorf("認証ファイルの種類 %q には対応していません (service_account / authorized_user のみ): %s", file.Type, path)
+	}
+	return &Client{backend: b, sheetName: sheetName}, nil
+}
+
+// FindDefaultCredentials はアプリケーションのデフォルト認証情報 (ADC) のファイルを探す。
+// 環境変数 GOOGLE_APPLICATION_CREDENTIALS、gcloud auth application-default login が作るファイルの順に探す。
+// (Compute Engine などのメタデータサーバには対応していない)
+func FindDefaultCredentials() (string, error) {
+	if p := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); p != "" {
+		if _, err := os.Stat(p); err != nil {
+			return "", fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS のファイルを開けません: %w", err)
+		}
+		return p, nil
+	}
+
+	dir := os.Getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gcloud")
+		}
+	}
+	if dir != "" {
+		p := filepath.Join(dir, "application_default_credentials.json")
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("アプリケーションのデフォルト認証情報が見つかりません。環境変数 GOOGLE_APPLICATION_CREDENTIALS を設定するか、gcloud auth application-default login を実行してください")
+}
+
+// commandTokenSource は外部コマンド (token_command) が出力するアクセストークンを使う。
+// コマンドはトークンだけか、{"access_token": "...", "expires_in": 3599} 形式の JSON を標準出力に出力する。
+type commandTokenSource struct {
+	command string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (ts *commandTokenSource) setTokenURI(uri string) {}
+
+func (ts *commandTokenSource) getToken() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && time.Now().Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCommandTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", ts.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", ts.command)
+	}
+	// gcloud などの確認メッセージが見えるよう、標準エラー出力はそのまま表示する
+	cmd.Stderr = os.Stderr
+	now := time.Now()
+	out, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("token_command が %s 以内に終了しませんでした", tokenCommandTimeout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("token_command の実行に失敗: %w", err)
+	}
+
+	out = bytes.TrimSpace(out)
+	token, lifetime := string(out), commandTokenLifetime
+	if bytes.HasPrefix(out, []byte("{")) {
+		var resp struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return "", fmt.Errorf("token_command の出力の解析に失敗: %w", err)
+		}
+		token = resp.AccessToken
+		if resp.ExpiresIn > 0 {
+			// 期限の1分前まで使う。期限が2分未満と短い場合は半分の時間だけ使う (0 以下にしない)
+			margin := min(60, resp.ExpiresIn/2)
+			lifetime = time.Duration(resp.ExpiresIn-margin) * time.Second
+		}
+	}
+	if token == "" || strings.ContainsAny(token, " \t\r\n") {
+		return "", fmt.Errorf("token_command がアクセストークンを出力しませんでした")
+	}
+
+	ts.token = token
+	ts.expiry = now.Add(lifetime)
+	return ts.token, nil
+}
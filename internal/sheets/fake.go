@@ -0,0 +1,523 @@
+package sheets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	mrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FakeServer はローカルTSVを Google スプレッドシートに見立てて、Sheets API・トークン取得・
+// 公開シートの CSV 出力を模擬する HTTP サーバ。サービスアカウントモードや公開シートモードを
+// Google に接続せずに試すために使う。
+//
+// 対応している API:
+//
+//	GET  /auth                                    login の認可画面 (すぐに許可してリダイレクトする)
+//	POST /token                                   アクセストークンの発行 (サービスアカウントの署名は検証しない)
+//	POST /revoke                                  logout でのトークンの無効化
+//	GET  /v4/spreadsheets/{id}                    シート情報
+//	GET  /v4/spreadsheets/{id}/values/{range}     値の読み込み
+//	POST /v4/spreadsheets/{id}/values:batchUpdate 値の書き込み
+//	POST /v4/spreadsheets/{id}:batchUpdate        列の挿入とヘッダの書き込み (書式のコピーは無視)
+//	GET  /spreadsheets/d/{id}/gviz/tq             公開シートの CSV
+//
+// スプレッドシートIDは何を指定しても同じ TSV を返す。
+type FakeServer struct {
+	path      string
+	sheetName string
+	token     string
+	failRate  float64
+	log       *log.Logger
+
+	mu sync.Mutex
+	// codes は発行した認可コードと PKCE の code_challenge、refresh は有効なリフレッシュトークン。
+	codes   map[string]string
+	refresh map[string]bool
+}
+
+// NewFakeServer は tsvPath の TSV をシート sheetName として公開する FakeServer を作る。
+func NewFakeServer(tsvPath, sheetName string) *FakeServer {
+	return &FakeServer{
+		path:      tsvPath,
+		sheetName: sheetName,
+		token:     fakeRandom("fake-"),
+		codes:     make(map[string]string),
+		refresh:   make(map[string]bool),
+	}
+}
+
+// FakeOAuthClientID は fake-sheets の login で使う OAuth クライアントID。
+const FakeOAuthClientID = "fake-sheets.apps.localhost"
+
+// fakeEmail は fake-sheets の login でログインしたことになるアカウント。
+const fakeEmail = "fake-user@localhost"
+
+// SetFailRate は rate の割合 (0〜1) のリクエストを 503 で失敗させる (再試行の確認用)。
+func (s *FakeServer) SetFailRate(rate float64) {
+	s.failRate = rate
+}
+
+// SetLog はリクエストごとのログの出力先を設定する。
+func (s *FakeServer) SetLog(w io.Writer) {
+	s.log = log.New(w, "", log.Ltime)
+}
+
+// statusRecorder はログ用に応答のステータスコードを記録する。
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (s *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.serve(rec, r)
+	if s.log != nil {
+		s.log.Printf("%s %s %d", r.Method, r.URL.Path, rec.status)
+	}
+}
+
+func (s *FakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	if s.failRate > 0 && mrand.Float64() < s.failRate {
+		w.Header().Set("Retry-After", "1")
+		fakeError(w, http.StatusServiceUnavailable, "UNAVAILABLE", "The service is currently unavailable.")
+		return
+	}
+
+	p := r.URL.Path
+	switch {
+	case p == "/auth":
+		s.handleAuth(w, r)
+	case p == "/token":
+		s.handleToken(w, r)
+	case p == "/revoke":
+		s.mu.Lock()
+		delete(s.refresh, r.FormValue("token"))
+		s.mu.Unlock()
+		fakeJSON(w, map[string]any{})
+	case strings.HasPrefix(p, "/v4/spreadsheets/"):
+		if r.Header.Get("Authorization") != "Bearer "+s.token {
+			fakeError(w, http.StatusUnauthorized, "UNAUTHENTICATED", "Request had invalid authentication credentials.")
+			return
+		}
+		s.handleSheets(w, r, strings.TrimPrefix(p, "/v4/spreadsheets/"))
+	case strings.HasPrefix(p, "/spreadsheets/d/") && strings.HasSuffix(p, "/gviz/tq"):
+		s.handlePublicCSV(w, r)
+	default:
+		fakeError(w, http.StatusNotFound, "NOT_FOUND", "Requested entity was not found.")
+	}
+}
+
+// handleAuth は Google の認可画面の代わりに、利用者の操作なしで許可したことにして redirect_uri に戻す。
+func (s *FakeServer) handleAuth(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	switch {
+	case q.Get("client_id") != FakeOAuthClientID:
+		fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "invalid_client: The OAuth client was not found.")
+		return
+	case err != nil || redirect.Scheme != "http" || redirect.Hostname() != "127.0.0.1":
+		fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "redirect_uri_mismatch")
+		return
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "code_challenge_method must be S256")
+		return
+	}
+
+	code := fakeRandom("code-")
+	s.mu.Lock()
+	s.codes[code] = q.Get("code_challenge")
+	s.mu.Unlock()
+
+	v := redirect.Query()
+	v.Set("code", code)
+	v.Set("state", q.Get("state"))
+	redirect.RawQuery = v.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *FakeServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		fakeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "method not allowed")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := map[string]any{
+		"access_token": s.token,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	}
+	switch r.FormValue("grant_type") {
+	case "urn:ietf:params:oauth:grant-type:jwt-bearer":
+	case "authorization_code":
+		// PKCE: code_verifier の SHA-256 が認可時の code_challenge と一致すること
+		challenge, ok := s.codes[r.FormValue("code")]
+		delete(s.codes, r.FormValue("code"))
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || base64URLEncode(sum[:]) != challenge {
+			fakeTokenError(w, "invalid_grant", "Malformed auth code or code verifier.")
+			return
+		}
+		refresh := fakeRandom("refresh-")
+		s.refresh[refresh] = true
+		resp["refresh_token"] = refresh
+		claims, _ := json.Marshal(map[string]string{"email": fakeEmail})
+		resp["id_token"] = "e30." + base64URLEncode(claims) + ".fake"
+	case "refresh_token":
+		if !s.refresh[r.FormValue("refresh_token")] {
+			fakeTokenError(w, "invalid_grant", "Token has been expired or revoked.")
+			return
+		}
+	default:
+		fakeTokenError(w, "unsupported_grant_type", "Invalid grant_type: "+r.FormValue("grant_type"))
+		return
+	}
+	fakeJSON(w, resp)
+}
+
+func fakeTokenError(w http.ResponseWriter, code, desc string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": desc})
+}
+
+func fakeRandom(prefix string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// handleSheets は Sheets API の要求を振り分ける。
+// TSV ファイルの読み書きは各ハンドラで s.mu を持ったまま行う (読んで書き戻す間に他の要求が割り込まないように)。
+func (s *FakeServer) handleSheets(w http.ResponseWriter, r *http.Request, rest string) {
+	id, op, _ := strings.Cut(rest, "/")
+	switch {
+	case op == "" && strings.HasSuffix(id, ":batchUpdate") && r.Method == http.MethodPost:
+		s.handleBatchUpdate(w, r)
+	case op == "" && r.Method == http.MethodGet:
+		fakeJSON(w, map[string]any{
+			"sheets": []map[string]any{
+				{"properties": map[string]any{"sheetId": 0, "title": s.sheetName}},
+			},
+		})
+	case op == "values:batchUpdate" && r.Method == http.MethodPost:
+		s.handleValuesBatchUpdate(w, r)
+	case strings.HasPrefix(op, "values/") && r.Method == http.MethodGet:
+		s.handleValuesGet(w, strings.TrimPrefix(op, "values/"))
+	default:
+		fakeError(w, http.StatusNotFound, "NOT_FOUND", "Requested entity was not found.")
+	}
+}
+
+func (s *FakeServer) handleValuesGet(w http.ResponseWriter, a1 string) {
+	rng, err := s.parseRange(a1)
+	if err != nil {
+		fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", err.Error())
+		return
+	}
+	s.mu.Lock()
+	rows, err := s.read()
+	s.mu.Unlock()
+	if err != nil {
+		fakeError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+
+	// Sheets API と同じく、行末の空セルと末尾の空行は返さない
+	var values [][]string
+	for row := rng.row1; row <= rng.row2 && row < len(rows); row++ {
+		var cells []string
+		for col := rng.col1; col <= rng.col2 && col < len(rows[row]); col++ {
+			cells = append(cells, rows[row][col])
+		}
+		for len(cells) > 0 && cells[len(cells)-1] == "" {
+			cells = cells[:len(cells)-1]
+		}
+		values = append(values, cells)
+	}
+	for len(values) > 0 && len(values[len(values)-1]) == 0 {
+		values = values[:len(values)-1]
+	}
+
+	resp := map[string]any{"range": a1, "majorDimension": "ROWS"}
+	if len(values) > 0 {
+		resp["values"] = values
+	}
+	fakeJSON(w, resp)
+}
+
+func (s *FakeServer) handleValuesBatchUpdate(w http.ResponseWriter, r *http.Request) {
+	var req batchUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid JSON payload received. "+err.Error())
+		return
+	}
+
+	cells := make(map[[2]int]string)
+	updated := 0
+	for _, d := range req.Data {
+		rng, err := s.parseRange(d.Range)
+		if err != nil {
+			fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", err.Error())
+			return
+		}
+		for i, row := range d.Values {
+			for j, v := range row {
+				cells[[2]int{rng.row1 + i, rng.col1 + j}] = v
+				updated++
+			}
+		}
+	}
+	s.mu.Lock()
+	err := writeTSVCells(s.path, cells)
+	s.mu.Unlock()
+	if err != nil {
+		fakeError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	fakeJSON(w, map[string]any{"totalUpdatedCells": updated})
+}
+
+// handleBatchUpdate は spreadsheets:batchUpdate のうち列の挿入 (insertDimension) と
+// ヘッダ行の書き込み (updateCells) だけを反映する。
+func (s *FakeServer) handleBatchUpdate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Requests []struct {
+			InsertDimension *struct {
+				Range struct {
+					Dimension  string `json:"dimension"`
+					StartIndex int    `json:"startIndex"`
+					EndIndex   int    `json:"endIndex"`
+				} `json:"range"`
+			} `json:"insertDimension"`
+			UpdateCells *struct {
+				Start struct {
+					RowIndex    int `json:"rowIndex"`
+					ColumnIndex int `json:"columnIndex"`
+				} `json:"start"`
+				Rows []struct {
+					Values []struct {
+						UserEnteredValue struct {
+							StringValue string `json:"stringValue"`
+						} `json:"userEnteredValue"`
+					} `json:"values"`
+				} `json:"rows"`
+			} `json:"updateCells"`
+		} `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid JSON payload received. "+err.Error())
+		return
+	}
+	for _, q := range req.Requests {
+		if d := q.InsertDimension; d != nil && (d.Range.Dimension != "COLUMNS" || d.Range.EndIndex <= d.Range.StartIndex) {
+			fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "fake-sheets は列の挿入のみ対応しています")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	err := writeTSVDocument(s.path, func(doc *tsvDocument) {
+		for _, q := range req.Requests {
+			switch {
+			case q.InsertDimension != nil:
+				rng := q.InsertDimension.Range
+				doc.insertColumns(rng.StartIndex, make([]string, rng.EndIndex-rng.StartIndex))
+			case q.UpdateCells != nil:
+				u := q.UpdateCells
+				for i, row := range u.Rows {
+					for j, v := range row.Values {
+						doc.set(u.Start.RowIndex+i, u.Start.ColumnIndex+j, v.UserEnteredValue.StringValue)
+					}
+				}
+			}
+		}
+	})
+	s.mu.Unlock()
+	if err != nil {
+		fakeError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	fakeJSON(w, map[string]any{"replies": []any{}})
+}
+
+func (s *FakeServer) handlePublicCSV(w http.ResponseWriter, r *http.Request) {
+	if sheet := r.URL.Query().Get("sheet"); sheet != s.sheetName {
+		fakeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("シート '%s' はありません", sheet))
+		return
+	}
+	s.mu.Lock()
+	rows, err := s.read()
+	s.mu.Unlock()
+	if err != nil {
+		fakeError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	for _, record := range rows {
+		cw.Write(record)
+	}
+	cw.Flush()
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// read は tsv_file と同じ読み込み (tsvBackend.getValues) でセルの値を返す。
+func (s *FakeServer) read() ([][]string, error) {
+	return (&tsvBackend{path: s.path}).getValues(s.sheetName + "!A:ZZ")
+}
+
+// fakeRange は A1 形式の範囲 (0-indexed、両端を含む)。
+type fakeRange struct {
+	row1, col1, row2, col2 int
+}
+
+// parseRange は「シート名!A1:ZZ」「シート名!1:1」「シート名!E12」形式の範囲を解析する。
+// シート名が違う場合は Sheets API と同じ "Unable to parse range" のエラーを返す。
+func (s *FakeServer) parseRange(a1 string) (fakeRange, error) {
+	unable := fmt.Errorf("Unable to parse range: %s", a1)
+
+	sheet, cells := a1, ""
+	if i := strings.LastIndex(a1, "!"); i >= 0 {
+		sheet, cells = a1[:i], a1[i+1:]
+	}
+	if len(sheet) >= 2 && sheet[0] == '\'' && sheet[len(sheet)-1] == '\'' {
+		sheet = strings.ReplaceAll(sheet[1:len(sheet)-1], "''", "'")
+	}
+	if sheet != s.sheetName {
+		return fakeRange{}, unable
+	}
+
+	rng := fakeRange{row2: math.MaxInt32, col2: math.MaxInt32}
+	if cells == "" {
+		return rng, nil
+	}
+	from, to, isRange := strings.Cut(cells, ":")
+	row, col, ok := parseA1Cell(from)
+	if !ok {
+		return fakeRange{}, unable
+	}
+	if row >= 0 {
+		rng.row1 = row
+	}
+	if col >= 0 {
+		rng.col1 = col
+	}
+	if !isRange {
+		rng.row2, rng.col2 = rng.row1, rng.col1
+		return rng, nil
+	}
+	if row, col, ok = parseA1Cell(to); !ok {
+		return fakeRange{}, unable
+	}
+	if row >= 0 {
+		rng.row2 = row
+	}
+	if col >= 0 {
+		rng.col2 = col
+	}
+	return rng, nil
+}
+
+// parseA1Cell は「E12」「E」「12」を行・列 (0-indexed、省略された方は -1) に変換する。
+func parseA1Cell(s string) (row, col int, ok bool) {
+	i := 0
+	col = 0
+	for i < len(s) && s[i] >= 'A' && s[i] <= 'Z' {
+		col = col*26 + int(s[i]-'A'+1)
+		i++
+	}
+	col--
+	row = -1
+	if i < len(s) {
+		n, err := strconv.Atoi(s[i:])
+		if err != nil || n < 1 {
+			return 0, 0, false
+		}
+		row = n - 1
+	}
+	return row, col, i > 0 || row >= 0
+}
+
+func fakeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func fakeError(w http.ResponseWriter, code int, status, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"code": code, "message": message, "status": status},
+	})
+}
+
+// WriteFakeCredentials は FakeServer 用のサービスアカウント認証ファイルを path に作る。
+// 秘密鍵はその場で生成したもので、Google では使えない。
+func WriteFakeCredentials(path, tokenURI string) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("秘密鍵の生成に失敗: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("秘密鍵の生成に失敗: %w", err)
+	}
+	data, err := json.MarshalIndent(map[string]string{
+		"type":         "service_account",
+		"client_email": "fake-sheets@localhost",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("認証ファイルの書き込みに失敗: %w", err)
+	}
+	return nil
+}
+
+// WriteFakeOAuthClient は FakeServer の login で使う OAuth クライアントファイルを path に作る。
+func WriteFakeOAuthClient(path, base string) error {
+	data, err := json.MarshalIndent(map[string]any{
+		"installed": map[string]string{
+			"client_id":     FakeOAuthClientID,
+			"client_secret": "fake-secret",
+			"auth_uri":      base + "/auth",
+			"token_uri":     base + "/token",
+		},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("OAuth クライアントファイルの書き込みに失敗: %w", err)
+	}
+	return nil
+}
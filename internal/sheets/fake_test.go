@@ -0,0 +1,161 @@
+package sheets
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/journal"
+	"atena_printer/internal/model"
+)
+
+// newFakeClient は TSV ファイル data を公開する FakeServer と、それを Sheets API として読み書きする Client を作る。
+func newFakeClient(t *testing.T, data string) (c *Client, tsvPath string, j *journal.Journal) {
+	t.Helper()
+	dir := t.TempDir()
+	tsvPath = filepath.Join(dir, "sheet.tsv")
+	if err := os.WriteFile(tsvPath, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(NewFakeServer(tsvPath, "住所録"))
+	t.Cleanup(srv.Close)
+
+	credPath := filepath.Join(dir, "cred.json")
+	if err := WriteFakeCredentials(credPath, srv.URL+"/token"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewAPI(Credentials{File: credPath}, "fake", "住所録")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetColumns(config.DefaultColumns)
+	c.SetStatusValues(config.DefaultStatusValues)
+	c.SetEndpoints(config.Endpoints{SheetsAPI: srv.URL + "/v4/spreadsheets"})
+	return c, tsvPath, journal.Open(filepath.Join(dir, "journal.jsonl"))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func findAddress(t *testing.T, addresses []model.Address, family string) model.Address {
+	t.Helper()
+	for _, addr := range addresses {
+		if addr.FamilyName == family {
+			return addr
+		}
+	}
+	t.Fatalf("%s が見つかりません", family)
+	return model.Address{}
+}
+
+func TestFakeServerMarkSentUndo(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		target   string
+		marked   string
+		edit     func(data string) string // mark-sent と undo の間のシートの編集
+		restored string                   // 空なら data
+	}{
+		{
+			name:   "空行のある TSV",
+			data:   "姓\t名\t郵便番号\t住所1\t2025送\t2026送\r\n佐藤\t花子\t1500001\t東京都\t○\t\r\n\r\n鈴木\t一郎\t5300001\t大阪府\t\t\r\n",
+			target: "鈴木",
+			marked: "姓\t名\t郵便番号\t住所1\t2025送\t2026送\r\n佐藤\t花子\t1500001\t東京都\t○\t\r\n\r\n鈴木\t一郎\t5300001\t大阪府\t\t○\r\n",
+		},
+		{
+			name:   "ID 列があれば取り消し前に行を挿入してもよい",
+			data:   "ID\t姓\t名\t郵便番号\t住所1\t2026送\n1\t佐藤\t花子\t1500001\t東京都\t\n2\t鈴木\t一郎\t5300001\t大阪府\t\n",
+			target: "鈴木",
+			marked: "ID\t姓\t名\t郵便番号\t住所1\t2026送\n1\t佐藤\t花子\t1500001\t東京都\t\n2\t鈴木\t一郎\t5300001\t大阪府\t○\n",
+			edit: func(data string) string {
+				return strings.Replace(data, "\n1\t", "\n3\t高橋\t美咲\t9800001\t宮城県\t\n1\t", 1)
+			},
+			restored: "ID\t姓\t名\t郵便番号\t住所1\t2026送\n3\t高橋\t美咲\t9800001\t宮城県\t\n1\t佐藤\t花子\t1500001\t東京都\t\n2\t鈴木\t一郎\t5300001\t大阪府\t\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, path, j := newFakeClient(t, tt.data)
+
+			addresses, _, err := c.ReadAddresses(2026)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetJournal(j, "mark-sent")
+			if err := c.MarkSent(2026, []model.Address{findAddress(t, addresses, tt.target)}); err != nil {
+				t.Fatal(err)
+			}
+			if got := readFile(t, path); got != tt.marked {
+				t.Fatalf("mark-sent 後:\n got  %q\n want %q", got, tt.marked)
+			}
+
+			if tt.edit != nil {
+				if err := os.WriteFile(path, []byte(tt.edit(tt.marked)), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			op, err := j.Find("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetJournal(j, "undo")
+			if err := c.Undo(op); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.restored
+			if want == "" {
+				want = tt.data
+			}
+			if got := readFile(t, path); got != want {
+				t.Errorf("undo 後:\n got  %q\n want %q", got, want)
+			}
+
+			// 取り消した操作は再び取り消せない
+			if _, err := j.Find(op.ID); err == nil {
+				t.Error("取り消し済みの操作が見つかります")
+			}
+		})
+	}
+}
+
+func TestFakeServerUndoConflict(t *testing.T) {
+	data := "姓\t名\t2026送\n佐藤\t花子\t\n"
+	c, path, j := newFakeClient(t, data)
+
+	addresses, _, err := c.ReadAddresses(2026)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetJournal(j, "mark-sent")
+	if err := c.MarkSent(2026, addresses); err != nil {
+		t.Fatal(err)
+	}
+
+	// 書き込んだ後にセルが手で変更されていたら取り消さない
+	edited := "姓\t名\t2026送\n佐藤\t花子\t済\n"
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	op, err := j.Find("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Undo(op); err == nil {
+		t.Error("Undo がエラーになりません")
+	}
+	if got := readFile(t, path); got != edited {
+		t.Errorf("Undo 失敗後にシートが変更されています: %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package sheets
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"atena_printer/internal/textenc"
+)
+
+// fileBackend は Excel などが出力した CSV / TSV を読み込む (読み取り専用)。
+// 文字コード (UTF-8 / UTF-8 BOM / CP932 / UTF-16LE) と区切り文字は内容から判定する。
+type fileBackend struct {
+	path string
+
+	// 最後に読み込んだときの判定結果 (describe で表示する)
+	encoding  textenc.Encoding
+	delimiter rune
+}
+
+// NewFile は CSV / TSV ファイルを読み込む Client を作る。
+func NewFile(path, sheetName string) *Client {
+	return &Client{
+		backend:   &fileBackend{path: path},
+		sheetName: sheetName,
+	}
+}
+
+func (b *fileBackend) target() string {
+	return b.path
+}
+
+func (b *fileBackend) describe() string {
+	if b.encoding == "" {
+		return "ローカルファイル " + b.path
+	}
+	return fmt.Sprintf("ローカルファイル %s (%s, %s)", b.path, textenc.DelimiterName(b.delimiter), b.encoding)
+}
+
+func (b *fileBackend) getValues(readRange string) ([][]string, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return nil, fmt.Errorf("住所録ファイルの読み込みに失敗: %w", err)
+	}
+
+	text, enc, err := textenc.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.path, err)
+	}
+	b.encoding = enc
+	b.delimiter = textenc.DetectDelimiter(text)
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = b.delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	values, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%sの解析に失敗: %w", textenc.DelimiterName(b.delimiter), err)
+	}
+
+	if strings.HasSuffix(readRange, "!1:1") {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return [][]string{values[0]}, nil
+	}
+
+	return values, nil
+}
@@ -0,0 +1,160 @@
+package sheets
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"atena_printer/internal/config"
+)
+
+// 年ステータス列の種類。
+const (
+	kindSent     = "sent"
+	kindReceived = "received"
+	kindMourning = "mourning"
+)
+
+// columnLetterPattern は列記号 (A, B, ..., ZZ) として扱う値。
+var columnLetterPattern = regexp.MustCompile(`^[A-Z]{1,2}$`)
+
+// layout はヘッダ行と列の対応 (config.Columns) から求めた各項目の列位置。
+// 列が無い項目は -1 (getCell で空文字になる)。
+type layout struct {
+	cols config.Columns
+
+	id, family, given, fullName, reading int
+	joint, honorific, postal             int
+	address1, address2, tags             int
+
+	years map[int]yearColumns
+	// yearPatterns は年ステータス列のヘッダに一致する正規表現 (種類ごと)。
+	yearPatterns map[string]*regexp.Regexp
+}
+
+// newLayout はヘッダ行から列位置を求める。姓 (または氏名) の列が無い場合はエラー。
+func newLayout(header []string, cols config.Columns) (*layout, error) {
+	idx := buildColumnIndex(header)
+	// 設定で指定された値だけ列記号としても扱う (既定の「ID」を列 ID と解釈しないため)
+	explicit := make(map[string]bool)
+	for _, v := range []string{cols.ID, cols.FamilyName, cols.GivenName, cols.FullName, cols.Reading, cols.JointNames,
+		cols.Honorific, cols.PostalCode, cols.Address1, cols.Address2, cols.Tags} {
+		if v != "" {
+			explicit[v] = true
+		}
+	}
+	cols = cols.WithDefaults()
+	find := func(spec string) int {
+		if i, ok := idx[spec]; ok {
+			return i
+		}
+		if explicit[spec] && columnLetterPattern.MatchString(spec) {
+			if _, col, err := parseCellRange(spec + "1"); err == nil {
+				return col
+			}
+		}
+		return -1
+	}
+
+	l := &layout{
+		cols:      cols,
+		id:        find(cols.ID),
+		family:    find(cols.FamilyName),
+		given:     find(cols.GivenName),
+		fullName:  -1,
+		reading:   find(cols.Reading),
+		joint:     find(cols.JointNames),
+		honorific: find(cols.Honorific),
+		postal:    find(cols.PostalCode),
+		address1:  find(cols.Address1),
+		address2:  find(cols.Address2),
+		tags:      find(cols.Tags),
+	}
+	if l.family < 0 {
+		l.fullName = find(cols.FullName)
+		if l.fullName < 0 {
+			return nil, fmt.Errorf("姓の列 (%s) も氏名の列 (%s) も見つかりません。設定の columns を確認してください", cols.FamilyName, cols.FullName)
+		}
+	}
+
+	l.yearPatterns = map[string]*regexp.Regexp{
+		kindSent:     yearPattern(cols.Sent),
+		kindReceived: yearPattern(cols.Received),
+		kindMourning: yearPattern(cols.Mourning),
+	}
+	l.years = l.findYearColumns(header)
+	return l, nil
+}
+
+// yearPattern は「{year}送」のようなパターンをヘッダに一致する正規表現にする。
+func yearPattern(pattern string) *regexp.Regexp {
+	before, after, _ := strings.Cut(pattern, "{year}")
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(before) + `(\d{4})` + regexp.QuoteMeta(after) + `$`)
+}
+
+// findYearColumns はヘッダにあるすべての年ステータス列を年ごとにまとめて返す。
+func (l *layout) findYearColumns(header []string) map[int]yearColumns {
+	result := make(map[int]yearColumns)
+	for i, h := range header {
+		for kind, re := range l.yearPatterns {
+			m := re.FindStringSubmatch(strings.TrimSpace(h))
+			if m == nil {
+				continue
+			}
+			year, _ := strconv.Atoi(m[1])
+			yc, ok := result[year]
+			if !ok {
+				yc = yearColumns{sent: -1, received: -1, mourning: -1}
+			}
+			switch kind {
+			case kindSent:
+				yc.sent = i
+			case kindReceived:
+				yc.received = i
+			case kindMourning:
+				yc.mourning = i
+			}
+			result[year] = yc
+		}
+	}
+	return result
+}
+
+// yearHeader は年ステータス列のヘッダ名を返す (例: 2026送)。
+func (l *layout) yearHeader(kind string, year int) string {
+	pattern := map[string]string{
+		kindSent:     l.cols.Sent,
+		kindReceived: l.cols.Received,
+		kindMourning: l.cols.Mourning,
+	}[kind]
+	return strings.Replace(pattern, "{year}", strconv.Itoa(year), 1)
+}
+
+// yearColumn は年ステータス列の列位置を返す。列が無い場合は -1。
+func (l *layout) yearColumn(kind string, year int) int {
+	yc, ok := l.years[year]
+	if !ok {
+		return -1
+	}
+	switch kind {
+	case kindSent:
+		return yc.sent
+	case kindReceived:
+		return yc.received
+	default:
+		return yc.mourning
+	}
+}
+
+// name は行の姓と名を返す。氏名が1列の場合は空白 (全角も可) で分ける。
+func (l *layout) name(row []string) (family, given string) {
+	if l.fullName < 0 {
+		return getCell(row, l.family), getCell(row, l.given)
+	}
+	parts := strings.Fields(strings.ReplaceAll(getCell(row, l.fullName), "　", " "))
+	if len(parts) == 0 {
+		return "", ""
+	}
+	return parts[0], strings.Join(parts[1:], "")
+}
@@ -0,0 +1,139 @@
+package sheets
+
+import (
+	"fmt"
+	"sort"
+
+	"atena_printer/internal/model"
+)
+
+// columnInserter は列の挿入に対応した backend。
+type columnInserter interface {
+	// insertColumns は列位置 at (0-indexed) に headers の列を挿入し、ヘッダ行に名前を書き込む。
+	// formatFrom[i] が 0 以上の場合、挿入後のその列から入力規則 (チェックボックスなど) と書式を
+	// i 番目の新しい列にコピーする (対応していない backend では無視する)。
+	insertColumns(at int, headers []string, formatFrom []int) error
+}
+
+// CanInsertColumns は new-year で列を挿入できるかどうかを返す。
+func (c *Client) CanInsertColumns() bool {
+	_, ok := c.backend.(columnInserter)
+	return ok
+}
+
+// PlanNewYear は year 年の年ステータス列 (送・受・喪中) のうち、無い列をどこに追加するかを返す。
+// 同じ年の列が一部あればその後ろ、無ければ前の年の列の後ろ、前の年も無ければ後の年の列の前、
+// 年ステータス列が1つも無ければ末尾に追加する。
+func (c *Client) PlanNewYear(year int) (*model.NewYearColumns, error) {
+	plan, _, err := c.planNewYear(year)
+	return plan, err
+}
+
+func (c *Client) planNewYear(year int) (*model.NewYearColumns, *layout, error) {
+	values, err := c.backend.getValues(c.sheetName + "!A1:ZZ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("シートの読み込みに失敗: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, nil, fmt.Errorf("ヘッダ行が空です")
+	}
+	header := values[0]
+	l, err := newLayout(header, c.columns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan := &model.NewYearColumns{At: -1}
+	for _, kind := range []string{kindSent, kindReceived, kindMourning} {
+		if l.yearColumn(kind, year) < 0 {
+			plan.Headers = append(plan.Headers, l.yearHeader(kind, year))
+		}
+	}
+	if len(plan.Headers) == 0 {
+		return plan, l, nil
+	}
+
+	// 挿入位置: 同じ年の列、無ければ year より前で最も新しい年の列の後ろ
+	var years []int
+	for y := range l.years {
+		if y <= year {
+			years = append(years, y)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+	for _, y := range years {
+		yc := l.years[y]
+		last := max(yc.sent, yc.received, yc.mourning)
+		if last >= 0 {
+			plan.At = last + 1
+			break
+		}
+	}
+	if plan.At < 0 {
+		// 前の年が無い場合は、後の年のうち最も古い年の列の前
+		first := -1
+		for y, yc := range l.years {
+			if y <= year {
+				continue
+			}
+			for _, col := range []int{yc.sent, yc.received, yc.mourning} {
+				if col >= 0 && (first < 0 || col < first) {
+					first = col
+				}
+			}
+		}
+		plan.At = first
+	}
+	if plan.At < 0 {
+		plan.At = len(header)
+	}
+	if plan.At > 0 {
+		plan.After = getCell(header, plan.At-1)
+	}
+	return plan, l, nil
+}
+
+// NewYear は PlanNewYear の列を挿入する。copyFormat の場合、前の年の同じ種類の列から
+// 入力規則 (チェックボックスなど) と書式をコピーする。
+// 列の挿入は undo では取り消せない (書き込み記録には残さない)。
+func (c *Client) NewYear(year int, copyFormat bool) (*model.NewYearColumns, error) {
+	ins, ok := c.backend.(columnInserter)
+	if !ok {
+		return nil, fmt.Errorf("このデータソース (%s) では列を追加できません。シートに列を追加してください", c.Describe())
+	}
+
+	plan, l, err := c.planNewYear(year)
+	if err != nil || len(plan.Headers) == 0 {
+		return plan, err
+	}
+
+	var formatFrom []int
+	if copyFormat {
+		prev := -1
+		for y := range l.years {
+			if y < year && y > prev {
+				prev = y
+			}
+		}
+		for _, h := range plan.Headers {
+			src := -1
+			if prev >= 0 {
+				for _, kind := range []string{kindSent, kindReceived, kindMourning} {
+					if l.yearHeader(kind, year) == h {
+						src = l.yearColumn(kind, prev)
+					}
+				}
+			}
+			// 挿入位置より後ろの列は挿入した列の数だけずれる
+			if src >= plan.At {
+				src += len(plan.Headers)
+			}
+			formatFrom = append(formatFrom, src)
+		}
+	}
+
+	if err := ins.insertColumns(plan.At, plan.Headers, formatFrom); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
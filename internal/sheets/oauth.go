@@ -0,0 +1,440 @@
+package sheets
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"atena_printer/internal/config"
+)
+
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+const (
+	defaultAuthURI   = "https://accounts.google.com/o/oauth2/v2/auth"
+	defaultTokenURI  = "https://oauth2.googleapis.com/token"
+	defaultRevokeURI = "https://oauth2.googleapis.com/revoke"
+)
+
+// loginTimeout はブラウザでの許可を待つ時間。
+const loginTimeout = 5 * time.Minute
+
+// ErrNotLoggedIn は oauth_client_file を使う設定で login がまだ行われていないことを表す。
+var ErrNotLoggedIn = errors.New("Google アカウントでログインしていません。先に atena_printer login を実行してください")
+
+// oauthClient は Google Cloud Console で作成した「デスクトップ アプリ」の OAuth クライアント。
+type oauthClient struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	AuthURI      string `json:"auth_uri"`
+	TokenURI     string `json:"token_uri"`
+	RevokeURI    string `json:"-"`
+}
+
+// loadOAuthClient は Console からダウンロードしたクライアントの JSON ({"installed": {...}}) を読み込み、
+// endpoints で URL を上書きする。
+func loadOAuthClient(path string, e config.Endpoints) (oauthClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return oauthClient{}, fmt.Errorf("OAuth クライアントファイルの読み込みに失敗: %w", err)
+	}
+	var file struct {
+		Installed *oauthClient `json:"installed"`
+		Web       *oauthClient `json:"web"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return oauthClient{}, fmt.Errorf("OAuth クライアントファイルの解析に失敗: %w", err)
+	}
+	if file.Installed == nil {
+		if file.Web != nil {
+			return oauthClient{}, fmt.Errorf("OAuth クライアントの種類が「ウェブ アプリケーション」です。「デスクトップ アプリ」のクライアントを作成してください")
+		}
+		return oauthClient{}, fmt.Errorf("OAuth クライアントファイルに installed の項目がありません (サービスアカウントの鍵は credentials_file に指定してください)")
+	}
+
+	c := *file.Installed
+	if c.ClientID == "" {
+		return oauthClient{}, fmt.Errorf("OAuth クライアントファイルに client_id がありません")
+	}
+	c.AuthURI = firstNonEmpty(e.Auth, c.AuthURI, defaultAuthURI)
+	c.TokenURI = firstNonEmpty(e.Token, c.TokenURI, defaultTokenURI)
+	c.RevokeURI = firstNonEmpty(e.Revoke, defaultRevokeURI)
+	return c, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// savedLogin は login で保存するリフレッシュトークン。
+type savedLogin struct {
+	ClientID     string    `json:"client_id"`
+	Email        string    `json:"email,omitempty"`
+	RefreshToken string    `json:"refresh_token"`
+	Created      time.Time `json:"created"`
+}
+
+// LoginFile はリフレッシュトークンの保存先 (ユーザーの設定ディレクトリ内) を返す。
+func LoginFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("設定ディレクトリが分かりません: %w", err)
+	}
+	return filepath.Join(dir, "atena_printer", "oauth_token.json"), nil
+}
+
+// loadLogin は clientID で保存したリフレッシュトークンを読み込む。無ければ ErrNotLoggedIn を返す。
+func loadLogin(clientID string) (savedLogin, error) {
+	path, err := LoginFile()
+	if err != nil {
+		return savedLogin{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return savedLogin{}, ErrNotLoggedIn
+	}
+	if err != nil {
+		return savedLogin{}, fmt.Errorf("ログイン情報の読み込みに失敗: %w", err)
+	}
+	var s savedLogin
+	if err := json.Unmarshal(data, &s); err != nil {
+		return savedLogin{}, fmt.Errorf("ログイン情報の解析に失敗 (%s): %w", path, err)
+	}
+	if s.ClientID != clientID || s.RefreshToken == "" {
+		return savedLogin{}, ErrNotLoggedIn
+	}
+	return s, nil
+}
+
+// saveLogin はリフレッシュトークンを本人だけが読み書きできるファイルに保存する。
+func saveLogin(s savedLogin) (string, error) {
+	path, err := LoginFile()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("ログイン情報の保存に失敗: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "oauth_token.tmp*")
+	if err != nil {
+		return "", fmt.Errorf("ログイン情報の保存に失敗: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	// CreateTemp は 0600 で作成するが、念のため明示する
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("ログイン情報の保存に失敗: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("ログイン情報の保存に失敗: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("ログイン情報の保存に失敗: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("ログイン情報の保存に失敗: %w", err)
+	}
+	return path, nil
+}
+
+// oauthTokenSource は保存したリフレッシュトークンからアクセストークンを取得する。
+type oauthTokenSource struct {
+	client  oauthClient
+	refresh string
+	http    *requester
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (ts *oauthTokenSource) setTokenURI(uri string) {
+	ts.client.TokenURI = uri
+}
+
+func (ts *oauthTokenSource) getToken() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && time.Now().Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	now := time.Now()
+	resp, err := ts.client.exchange(ts.http, true, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {ts.refresh},
+	})
+	if err != nil {
+		return "", err
+	}
+	ts.token = resp.AccessToken
+	ts.expiry = now.Add(time.Duration(resp.ExpiresIn-60) * time.Second)
+	return ts.token, nil
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+}
+
+// exchange はトークンエンドポイントに form を送る。
+func (c oauthClient) exchange(r *requester, idempotent bool, form url.Values) (oauthTokenResponse, error) {
+	form.Set("client_id", c.ClientID)
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+	encoded := form.Encode()
+	body, err := r.do("トークン取得", idempotent, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.TokenURI, strings.NewReader(encoded))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+	var resp oauthTokenResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return oauthTokenResponse{}, fmt.Errorf("トークン応答の解析に失敗: %w", err)
+	}
+	return resp, nil
+}
+
+// NewOAuth は login で保存した Google アカウントの権限で Sheets API を読み書きする Client を作る。
+func NewOAuth(clientFile, spreadsheetID, sheetName string) (*Client, error) {
+	client, err := loadOAuthClient(clientFile, config.Endpoints{})
+	if err != nil {
+		return nil, err
+	}
+	login, err := loadLogin(client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newRequester()
+	r.account = login.Email
+	r.cred = credLogin
+	return &Client{
+		backend: &apiBackend{
+			ts:            &oauthTokenSource{client: client, refresh: login.RefreshToken, http: r},
+			http:          r,
+			base:          defaultSheetsAPIBase,
+			spreadsheetID: spreadsheetID,
+			sheetName:     sheetName,
+		},
+		sheetName: sheetName,
+	}, nil
+}
+
+// Login はブラウザで Google アカウントにログインしてもらい、リフレッシュトークンを保存する。
+// 認可コードはループバックアドレスで受け取り、PKCE (S256) で横取りを防ぐ。
+// open には認可 URL をブラウザで開く関数を渡す (nil または失敗した場合は URL を表示するだけ)。
+func Login(ctx context.Context, clientFile string, e config.Endpoints, open func(string) error, out io.Writer) (email, path string, err error) {
+	client, err := loadOAuthClient(clientFile, e)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier := randomString(32)
+	challenge := sha256.Sum256([]byte(verifier))
+	state := randomString(16)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", "", fmt.Errorf("ログインの応答を受け取るポートを開けません: %w", err)
+	}
+	redirectURI := "http://" + ln.Addr().String() + "/"
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			// このログインの応答ではない (別のタブやページからのアクセス) ので、待ち続ける
+			http.Error(w, "ログインの応答が不正です (state が一致しません)", http.StatusBadRequest)
+			return
+		}
+		var res result
+		switch {
+		case q.Get("error") == "access_denied":
+			res.err = fmt.Errorf("ログインがキャンセルされました")
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("ログインに失敗: %s", q.Get("error"))
+		case q.Get("code") == "":
+			res.err = fmt.Errorf("ログインの応答に認可コードがありません")
+		default:
+			res.code = q.Get("code")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if res.err != nil {
+			fmt.Fprintf(w, "<p>%s</p>", html.EscapeString(res.err.Error()))
+		} else {
+			fmt.Fprint(w, "<p>ログインしました。このウィンドウを閉じてターミナルに戻ってください。</p>")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	authURL := client.AuthURI + "?" + url.Values{
+		"client_id":             {client.ClientID},
+		"redirect_uri":          {redirectURI},
+		"response_type":         {"code"},
+		"scope":                 {"openid email " + sheetsScope},
+		"code_challenge":        {base64URLEncode(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+		"access_type":           {"offline"},
+		"prompt":                {"consent"},
+	}.Encode()
+
+	fmt.Fprintln(out, "ブラウザで Google アカウントにログインし、スプレッドシートへのアクセスを許可してください。")
+	if open != nil && open(authURL) == nil {
+		fmt.Fprintln(out, "ブラウザが開かない場合は、次の URL を開いてください:")
+	} else {
+		fmt.Fprintln(out, "次の URL をブラウザで開いてください:")
+	}
+	fmt.Fprintf(out, "\n  %s\n\n", authURL)
+
+	ctx, cancel := context.WithTimeout(ctx, loginTimeout)
+	defer cancel()
+	var res result
+	select {
+	case res = <-results:
+	case <-ctx.Done():
+		return "", "", fmt.Errorf("ログインを待つ間にタイムアウトしました: %w", ctx.Err())
+	}
+	if res.err != nil {
+		return "", "", res.err
+	}
+
+	// 認可コードは1回しか使えないため再試行しない
+	resp, err := client.exchange(newRequester(), false, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"code_verifier": {verifier},
+		"redirect_uri":  {redirectURI},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if resp.RefreshToken == "" {
+		return "", "", fmt.Errorf("リフレッシュトークンを受け取れませんでした。もう一度 login を実行してください")
+	}
+
+	email = idTokenEmail(resp.IDToken)
+	path, err = saveLogin(savedLogin{
+		ClientID:     client.ClientID,
+		Email:        email,
+		RefreshToken: resp.RefreshToken,
+		Created:      time.Now(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return email, path, nil
+}
+
+// Logout は保存したリフレッシュトークンを無効にして削除する。ログインしていなければ false を返す。
+// 無効化に失敗してもファイルは削除する (Google アカウントの設定から手動で取り消せる)。
+func Logout(clientFile string, e config.Endpoints, warn io.Writer) (bool, error) {
+	client, err := loadOAuthClient(clientFile, e)
+	if err != nil {
+		return false, err
+	}
+	login, err := loadLogin(client.ClientID)
+	if errors.Is(err, ErrNotLoggedIn) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r := newRequester()
+	form := url.Values{"token": {login.RefreshToken}}.Encode()
+	_, err = r.do("ログインの取り消し", true, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", client.RevokeURI, strings.NewReader(form))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
+	if err != nil {
+		fmt.Fprintf(warn, "警告: トークンの無効化に失敗しました (Google アカウントの「サードパーティ製のアプリとサービス」から取り消せます): %s\n", firstLine(err))
+	}
+
+	path, err := LoginFile()
+	if err != nil {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		return false, fmt.Errorf("ログイン情報の削除に失敗: %w", err)
+	}
+	return true, nil
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64URLEncode(b)
+}
+
+// idTokenEmail は ID トークンからメールアドレスを取り出す。
+// トークンエンドポイントから直接受け取ったものなので署名は検証しない。
+func idTokenEmail(idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Email string `json:"email"`
+	}
+	json.Unmarshal(payload, &claims)
+	return claims.Email
+}
@@ -0,0 +1,97 @@
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"atena_printer/internal/config"
+)
+
+// publicCSVBackend は「リンクを知っている全員が閲覧可」のシートを CSV として読み込む (読み取り専用)。
+type publicCSVBackend struct {
+	http          *requester
+	spreadsheetID string
+	sheetName     string
+	url           string
+}
+
+const defaultPublicCSVBase = "https://docs.google.com/spreadsheets/d"
+
+// NewPublicCSV は公開シートを読み込む Client を作る。
+func NewPublicCSV(spreadsheetID, sheetName string) *Client {
+	b := &publicCSVBackend{
+		http:          newRequester(),
+		spreadsheetID: spreadsheetID,
+		sheetName:     sheetName,
+	}
+	b.setBase(defaultPublicCSVBase)
+	return &Client{
+		backend:   b,
+		sheetName: sheetName,
+	}
+}
+
+func (b *publicCSVBackend) setBase(base string) {
+	b.url = fmt.Sprintf(
+		"%s/%s/gviz/tq?tqx=out:csv&sheet=%s",
+		strings.TrimRight(base, "/"),
+		url.PathEscape(b.spreadsheetID),
+		url.QueryEscape(b.sheetName),
+	)
+}
+
+func (b *publicCSVBackend) setEndpoints(e config.Endpoints) {
+	if e.PublicCSV != "" {
+		b.setBase(e.PublicCSV)
+	}
+}
+
+func (b *publicCSVBackend) target() string {
+	return b.spreadsheetID + "/" + b.sheetName
+}
+
+func (b *publicCSVBackend) describe() string {
+	return fmt.Sprintf("公開シート %s / %s", b.spreadsheetID, b.sheetName)
+}
+
+func (b *publicCSVBackend) requester() *requester {
+	return b.http
+}
+
+func (b *publicCSVBackend) getValues(readRange string) ([][]string, error) {
+	body, err := b.http.do("公開シートの読み込み", true, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", b.url, nil)
+	})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && !apiErr.Temporary() {
+			return nil, fmt.Errorf(
+				"公開シートの読み込みに失敗 (HTTP %d)。シートを『リンクを知っている全員が閲覧可』にしているか確認してください",
+				apiErr.StatusCode,
+			)
+		}
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	values, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("公開CSVの解析に失敗: %w", err)
+	}
+
+	if strings.HasSuffix(readRange, "!1:1") {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return [][]string{values[0]}, nil
+	}
+
+	return values, nil
+}
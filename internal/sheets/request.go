@@ -0,0 +1,305 @@
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy は Google API 呼び出しのタイムアウトと再試行の設定。
+type RetryPolicy struct {
+	Timeout    time.Duration // 1回のリクエストのタイムアウト
+	MaxRetries int           // 再試行の回数 (0 なら再試行しない)
+	BaseDelay  time.Duration // 最初の再試行までの待ち時間 (以降は倍にしていく)
+	MaxDelay   time.Duration // 待ち時間の上限 (Retry-After の指定はこれを超えても従う)
+}
+
+// DefaultRetryPolicy は既定のタイムアウトと再試行の設定。
+var DefaultRetryPolicy = RetryPolicy{
+	Timeout:    30 * time.Second,
+	MaxRetries: 4,
+	BaseDelay:  time.Second,
+	MaxDelay:   30 * time.Second,
+}
+
+// requester は Google API へのリクエストを送る。
+// 429 / 500 / 502 / 503 / 504 と通信エラーは、待ち時間を倍にしながら (ゆらぎを加えて) 再試行する。
+type requester struct {
+	client *http.Client
+	policy RetryPolicy
+	ctx    context.Context
+	warn   io.Writer
+
+	// account は 403 の案内に表示するアカウント (サービスアカウントのメールアドレスなど)。
+	account string
+	// cred は認証方法 (認証エラーの案内が変わる)。
+	cred credentialKind
+}
+
+// credentialKind は API の認証方法。
+type credentialKind int
+
+const (
+	credServiceAccount credentialKind = iota // credentials_file のサービスアカウント
+	credLogin                                // login で保存した Google アカウント
+	credAuthorizedUser                       // gcloud auth application-default login のアカウント
+	credCommand                              // token_command が出力するトークン
+)
+
+func newRequester() *requester {
+	return &requester{
+		client: &http.Client{},
+		policy: DefaultRetryPolicy,
+		ctx:    context.Background(),
+	}
+}
+
+// httpBackend は requester を使う backend。
+type httpBackend interface {
+	requester() *requester
+}
+
+// SetRetryPolicy は API 呼び出しのタイムアウトと再試行の設定を変更する。
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	if b, ok := c.backend.(httpBackend); ok {
+		b.requester().policy = p
+	}
+}
+
+// SetContext は API 呼び出しに使う context を設定する (キャンセルすると再試行の待ちも中断する)。
+func (c *Client) SetContext(ctx context.Context) {
+	if b, ok := c.backend.(httpBackend); ok {
+		b.requester().ctx = ctx
+	}
+}
+
+// APIError は Google API のエラー応答。
+type APIError struct {
+	Op         string // 操作 (「シートの読み込み」など)
+	StatusCode int
+	Status     string // Google のエラー種別 (PERMISSION_DENIED など)
+	Message    string
+	Hint       string // 対処方法
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%sに失敗 (HTTP %d", e.Op, e.StatusCode)
+	if e.Status != "" {
+		msg += " " + e.Status
+	}
+	msg += ")"
+	if e.Hint != "" {
+		msg += ": " + e.Hint
+	}
+	if e.Message != "" {
+		msg += "\n  詳細: " + e.Message
+	}
+	return msg
+}
+
+// Temporary は時間をおけば成功する可能性があるエラーかどうかを返す。
+func (e *APIError) Temporary() bool {
+	return retryableStatus(e.StatusCode)
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do はリクエストを送り、HTTP 200 の応答本文を返す。build は再試行のたびに呼ばれる。
+// idempotent が false のリクエスト (列の挿入など) は、処理されていないことが確実な 429 だけ再試行する。
+func (r *requester) do(op string, idempotent bool, build func(ctx context.Context) (*http.Request, error)) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := r.once(op, build)
+		if err == nil {
+			return body, nil
+		}
+		if r.ctx.Err() != nil {
+			return nil, fmt.Errorf("%sを中断しました: %w", op, r.ctx.Err())
+		}
+
+		var apiErr *APIError
+		var bErr *buildError
+		retry := false
+		switch {
+		case errors.As(err, &bErr): // トークンの取得失敗など、送る前のエラー
+			return nil, bErr.err
+		case errors.As(err, &apiErr):
+			retry = apiErr.StatusCode == http.StatusTooManyRequests || (idempotent && apiErr.Temporary())
+		default: // 通信エラー・タイムアウト
+			retry = idempotent
+		}
+		if !retry || attempt >= r.policy.MaxRetries {
+			return nil, err
+		}
+
+		delay := r.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if r.warn != nil {
+			fmt.Fprintf(r.warn, "%s\n  %s後に再試行します (%d/%d)\n", firstLine(err), delay.Round(100*time.Millisecond), attempt+1, r.policy.MaxRetries)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%sを中断しました: %w", op, r.ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// buildError はリクエストを組み立てる段階のエラー (再試行しない)。
+type buildError struct{ err error }
+
+func (e *buildError) Error() string { return e.err.Error() }
+func (e *buildError) Unwrap() error { return e.err }
+
+// once は1回分のリクエストを送る。エラー応答の場合は Retry-After の待ち時間も返す。
+func (r *requester) once(op string, build func(ctx context.Context) (*http.Request, error)) ([]byte, time.Duration, error) {
+	ctx := r.ctx
+	if r.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.policy.Timeout)
+		defer cancel()
+	}
+
+	req, err := build(ctx)
+	if err != nil {
+		return nil, 0, &buildError{err}
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && r.ctx.Err() == nil {
+			return nil, 0, fmt.Errorf("%sがタイムアウトしました (%s)", op, r.policy.Timeout)
+		}
+		return nil, 0, fmt.Errorf("%sに失敗 (通信エラー): %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%sの応答の読み込みに失敗: %w", op, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return body, 0, nil
+	}
+	return nil, parseRetryAfter(resp.Header.Get("Retry-After")), r.classify(op, resp.StatusCode, body)
+}
+
+// backoff は attempt 回目 (0 始まり) の再試行までの待ち時間を返す。
+// BaseDelay × 2^attempt (上限 MaxDelay) の半分から全体までの間でゆらぎを加える。
+func (r *requester) backoff(attempt int) time.Duration {
+	d := r.policy.BaseDelay << attempt
+	if d <= 0 || (r.policy.MaxDelay > 0 && d > r.policy.MaxDelay) {
+		d = r.policy.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter は Retry-After ヘッダ (秒数か日時) を待ち時間にする。
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// classify はエラー応答を APIError にし、状態に応じた対処方法を付ける。
+func (r *requester) classify(op string, code int, body []byte) *APIError {
+	e := &APIError{Op: op, StatusCode: code}
+
+	// Sheets API: {"error": {"code": 403, "message": "...", "status": "PERMISSION_DENIED"}}
+	// トークン: {"error": "invalid_grant", "error_description": "..."}
+	var google struct {
+		Error json.RawMessage `json:"error"`
+		Desc  string          `json:"error_description"`
+	}
+	if json.Unmarshal(body, &google) == nil && len(google.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		}
+		if json.Unmarshal(google.Error, &detail) == nil {
+			e.Message, e.Status = detail.Message, detail.Status
+		} else {
+			var s string
+			json.Unmarshal(google.Error, &s)
+			e.Status, e.Message = s, google.Desc
+		}
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+		if len(e.Message) > 300 {
+			e.Message = e.Message[:300] + "..."
+		}
+	}
+
+	switch {
+	case code == http.StatusBadRequest && strings.Contains(e.Message, "Unable to parse range"):
+		e.Hint = "シートが見つかりません。sheet_name がスプレッドシートのシート名 (タブ名) と一致しているか確認してください"
+	case r.cred == credLogin && (code == http.StatusUnauthorized || (code == http.StatusBadRequest && e.Status == "invalid_grant")):
+		e.Hint = "ログインの有効期限が切れたか、取り消されています。atena_printer login でログインし直してください"
+	case r.cred == credAuthorizedUser && (code == http.StatusUnauthorized || (code == http.StatusBadRequest && e.Status == "invalid_grant")):
+		e.Hint = "gcloud のログインの有効期限が切れたか、取り消されています。gcloud auth application-default login でログインし直してください"
+	case r.cred == credCommand && code == http.StatusUnauthorized:
+		e.Hint = "token_command が出力したアクセストークンが無効です。コマンドを単独で実行して、有効なトークンが出力されるか確認してください"
+	case code == http.StatusForbidden && strings.Contains(e.Message, "insufficient authentication scopes"):
+		e.Hint = "アクセストークンに Google Sheets のスコープがありません。" + sheetsScope + " を含めてトークンを取得し直してください" +
+			" (gcloud の場合は gcloud auth application-default login --scopes=" + sheetsScope + ",https://www.googleapis.com/auth/cloud-platform)"
+	case code == http.StatusBadRequest && e.Status == "invalid_grant":
+		e.Hint = "認証に失敗しました。credentials_file の鍵が無効になっていないか、PC の時刻がずれていないか確認してください"
+	case code == http.StatusUnauthorized:
+		e.Hint = "認証に失敗しました。credentials_file の鍵が無効になっていないか確認してください"
+	case code == http.StatusForbidden && (e.Status == "SERVICE_DISABLED" || strings.Contains(e.Message, "has not been used") || strings.Contains(e.Message, "is disabled")):
+		e.Hint = "Google Cloud のプロジェクトで Google Sheets API を有効にしてください"
+	case code == http.StatusForbidden:
+		who := "サービスアカウントのメールアドレス"
+		if r.cred != credServiceAccount {
+			who = "使用している Google アカウント"
+		}
+		if r.account != "" {
+			who = r.account
+		}
+		e.Hint = fmt.Sprintf("スプレッドシートへのアクセス権がありません。スプレッドシートの「共有」で %s を編集者として追加してください", who)
+	case code == http.StatusNotFound:
+		e.Hint = "スプレッドシートが見つかりません。spreadsheet_id (URL の /d/ と /edit の間の文字列) を確認してください"
+	case code == http.StatusTooManyRequests:
+		e.Hint = "API の利用上限に達しました。しばらく待ってから再実行してください"
+	case code >= 500:
+		e.Hint = "Google 側の一時的なエラーです。しばらく待ってから再実行してください"
+	}
+	return e
+}
+
+func firstLine(err error) string {
+	s := err.Error()
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
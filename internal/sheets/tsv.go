@@ -0,0 +1,316 @@
+package sheets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"atena_printer/internal/journal"
+	"atena_printer/internal/textenc"
+)
+
+// tsvBackend は Google Spreadsheet からダウンロードしたローカルTSVを読み書きする。
+type tsvBackend struct {
+	path string
+}
+
+// NewTSV はローカルTSVファイルを読み書きする Client を作る。
+func NewTSV(tsvFile, sheetName string) *Client {
+	return &Client{
+		backend:   &tsvBackend{path: tsvFile},
+		sheetName: sheetName,
+	}
+}
+
+func (b *tsvBackend) target() string {
+	if abs, err := filepath.Abs(b.path); err == nil {
+		return abs
+	}
+	return b.path
+}
+
+func (b *tsvBackend) describe() string {
+	return "ローカルTSV " + b.path
+}
+
+func (b *tsvBackend) canAddColumns() bool {
+	return true
+}
+
+// getValues は TSV を書き込み時と同じ parseTSVDocument で読み込む。
+// 空行も1行として数えるので、行番号は writeCells で書き換える行と一致する。
+func (b *tsvBackend) getValues(readRange string) ([][]string, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return nil, fmt.Errorf("TSVファイルの読み込みに失敗: %w", err)
+	}
+
+	doc, err := parseTSVDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.path, err)
+	}
+	values := doc.values()
+
+	if strings.HasSuffix(readRange, "!1:1") {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return [][]string{values[0]}, nil
+	}
+
+	return values, nil
+}
+
+func (b *tsvBackend) writeCells(changes []journal.Change) error {
+	cells := make(map[[2]int]string, len(changes))
+	for _, ch := range changes {
+		row, col, err := parseCellRange(ch.Range)
+		if err != nil {
+			return err
+		}
+		cells[[2]int{row - 1, col}] = ch.New
+	}
+	return writeTSVCells(b.path, cells)
+}
+
+// tsvDocument は TSV ファイルを書き換えるための表現。
+// 各セルは元ファイル上の表記 (引用符を含む) のまま保持し、書き換えたセル以外は
+// 行末の改行コードも含めて元のバイト列をそのまま書き戻す。
+// 文字コード (UTF-8 / BOM付き / Shift_JIS / UTF-16LE) は読み込み時に判定し、書き戻す時も同じにする。
+type tsvDocument struct {
+	enc     textenc.Encoding
+	records [][]string // セルの元の表記
+	ends    []string   // 各行の行末 ("\r\n" / "\n" / 最終行は "" の場合あり)
+}
+
+func parseTSVDocument(raw []byte) (*tsvDocument, error) {
+	text, enc, err := textenc.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	doc := &tsvDocument{enc: enc}
+	data := []byte(text)
+
+	var record []string
+	var field strings.Builder
+	inQuotes := false
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case inQuotes:
+			field.WriteByte(b)
+			if b == '"' {
+				if i+1 < len(data) && data[i+1] == '"' {
+					field.WriteByte('"')
+					i++
+				} else {
+					inQuotes = false
+				}
+			}
+		case b == '"' && field.Len() == 0:
+			field.WriteByte(b)
+			inQuotes = true
+		case b == '\t':
+			record = append(record, field.String())
+			field.Reset()
+		case b == '\n' || (b == '\r' && i+1 < len(data) && data[i+1] == '\n'):
+			end := "\n"
+			if b == '\r' {
+				end = "\r\n"
+				i++
+			}
+			record = append(record, field.String())
+			field.Reset()
+			doc.records = append(doc.records, record)
+			doc.ends = append(doc.ends, end)
+			record = nil
+		default:
+			field.WriteByte(b)
+		}
+	}
+	if field.Len() > 0 || record != nil {
+		record = append(record, field.String())
+		doc.records = append(doc.records, record)
+		doc.ends = append(doc.ends, "")
+	}
+	return doc, nil
+}
+
+// newline はファイル内で使われている改行コード (新しい行に使う)。
+func (d *tsvDocument) newline() string {
+	for _, e := range d.ends {
+		if e != "" {
+			return e
+		}
+	}
+	return "\n"
+}
+
+// cell は行 row (0-indexed)、列 col のセルの値を返す。
+func (d *tsvDocument) cell(row, col int) string {
+	if row >= len(d.records) || col >= len(d.records[row]) {
+		return ""
+	}
+	return unquoteTSVField(d.records[row][col])
+}
+
+// values はすべてのセルの値を行ごとに返す (空行は空のセル1つの行になる)。
+func (d *tsvDocument) values() [][]string {
+	values := make([][]string, len(d.records))
+	for row, record := range d.records {
+		values[row] = make([]string, len(record))
+		for col := range record {
+			values[row][col] = d.cell(row, col)
+		}
+	}
+	return values
+}
+
+// set は行 row (0-indexed)、列 col のセルを書き換える。足りない行・列は空セルで補う。
+func (d *tsvDocument) set(row, col int, value string) {
+	for len(d.records) <= row {
+		if n := len(d.ends); n > 0 && d.ends[n-1] == "" {
+			d.ends[n-1] = d.newline()
+		}
+		d.records = append(d.records, []string{""})
+		d.ends = append(d.ends, d.newline())
+	}
+	for len(d.records[row]) <= col {
+		d.records[row] = append(d.records[row], "")
+	}
+	d.records[row][col] = quoteTSVField(value)
+}
+
+// bytes は読み込んだ時の文字コードでファイルの内容を返す。
+func (d *tsvDocument) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	for i, record := range d.records {
+		buf.WriteString(strings.Join(record, "\t"))
+		buf.WriteString(d.ends[i])
+	}
+	return textenc.Encode(buf.Bytes(), d.enc)
+}
+
+func unquoteTSVField(raw string) string {
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		return strings.ReplaceAll(raw[1:len(raw)-1], `""`, `"`)
+	}
+	return raw
+}
+
+func quoteTSVField(value string) string {
+	if strings.ContainsAny(value, "\t\r\n\"") {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return value
+}
+
+// insertColumns はヘッダ行の列位置 at に headers を挿入する。
+// データ行は at の位置まで列がある行だけ空のセルを挿入する (それより短い行はずれないため)。
+func (d *tsvDocument) insertColumns(at int, headers []string) {
+	for i, record := range d.records {
+		if i > 0 && len(record) < at {
+			continue
+		}
+		for len(record) < at {
+			record = append(record, "")
+		}
+		cells := make([]string, len(headers))
+		if i == 0 {
+			for j, h := range headers {
+				cells[j] = quoteTSVField(h)
+			}
+		}
+		d.records[i] = append(record[:at:at], append(cells, record[at:]...)...)
+	}
+}
+
+func (b *tsvBackend) insertColumns(at int, headers []string, formatFrom []int) error {
+	return writeTSVDocument(b.path, func(doc *tsvDocument) {
+		doc.insertColumns(at, headers)
+	})
+}
+
+// writeTSVCells は TSV ファイルの指定セルを書き換える。
+func writeTSVCells(path string, cells map[[2]int]string) error {
+	return writeTSVDocument(path, func(doc *tsvDocument) {
+		for rc, value := range cells {
+			doc.set(rc[0], rc[1], value)
+		}
+	})
+}
+
+// writeTSVDocument は TSV ファイルを edit で書き換える。
+// 一時ファイルに書き出してから置き換え、元のファイルは .bak として残す。
+func writeTSVDocument(path string, edit func(doc *tsvDocument)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("TSVファイルの読み込みに失敗: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	doc, err := parseTSVDocument(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	edit(doc)
+	out, err := doc.bytes()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := copyFile(path, path+".bak", info.Mode().Perm()); err != nil {
+		return fmt.Errorf("バックアップの作成に失敗: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("一時ファイルの作成に失敗: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		return fmt.Errorf("一時ファイルへの書き込みに失敗: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("一時ファイルへの書き込みに失敗: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("一時ファイルへの書き込みに失敗: %w", err)
+	}
+	if err := os.Chmod(tmpName, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("TSVファイルの置き換えに失敗: %w", err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,143 @@
+package sheets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+
+	"atena_printer/internal/textenc"
+)
+
+func TestParseTSVDocument(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		values [][]string
+	}{
+		{
+			name:   "LF・最終行の改行なし",
+			data:   "姓\t名\n佐藤\t花子",
+			values: [][]string{{"姓", "名"}, {"佐藤", "花子"}},
+		},
+		{
+			name:   "CRLF と空行",
+			data:   "姓\t名\r\n\r\n佐藤\t花子\r\n",
+			values: [][]string{{"姓", "名"}, {""}, {"佐藤", "花子"}},
+		},
+		{
+			name:   "引用符で囲んだセル",
+			data:   "姓\t連名\n佐藤\t\"太郎\n次郎\"\n鈴木\t\"a\"\"b\"\tc\"d\n",
+			values: [][]string{{"姓", "連名"}, {"佐藤", "太郎\n次郎"}, {"鈴木", `a"b`, `c"d`}},
+		},
+		{
+			name:   "BOM",
+			data:   "\xEF\xBB\xBF姓\t名\n",
+			values: [][]string{{"姓", "名"}},
+		},
+		{
+			name:   "空",
+			data:   "",
+			values: [][]string{},
+		},
+	}
+	for _, tt := range tests {
+		doc, err := parseTSVDocument([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := doc.values(); !reflect.DeepEqual(got, tt.values) {
+			t.Errorf("%s: values = %q, want %q", tt.name, got, tt.values)
+		}
+		// 書き換えなければ元のバイト列のまま
+		out, err := doc.bytes()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(out) != tt.data {
+			t.Errorf("%s: bytes = %q, want %q", tt.name, out, tt.data)
+		}
+	}
+}
+
+func TestTSVDocumentEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		edit func(doc *tsvDocument)
+		want string
+	}{
+		{
+			name: "書き換えたセルだけ変わる",
+			data: "姓\t2026送\r\n\r\n佐藤\t\r\n\"鈴\"\"木\"\t\r\n",
+			edit: func(doc *tsvDocument) { doc.set(2, 1, "○") },
+			want: "姓\t2026送\r\n\r\n佐藤\t○\r\n\"鈴\"\"木\"\t\r\n",
+		},
+		{
+			name: "足りない行と列を補う",
+			data: "姓\t名\n佐藤",
+			edit: func(doc *tsvDocument) { doc.set(2, 2, "a\tb") },
+			want: "姓\t名\n佐藤\n\t\t\"a\tb\"\n",
+		},
+		{
+			name: "列の挿入",
+			data: "姓\t名\t住所\n佐藤\t花子\t東京\n\n鈴木\n",
+			edit: func(doc *tsvDocument) { doc.insertColumns(2, []string{"2026送", "2026受"}) },
+			want: "姓\t名\t2026送\t2026受\t住所\n佐藤\t花子\t\t\t東京\n\n鈴木\n",
+		},
+	}
+	for _, tt := range tests {
+		doc, err := parseTSVDocument([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		tt.edit(doc)
+		out, err := doc.bytes()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(out) != tt.want {
+			t.Errorf("%s:\n got  %q\n want %q", tt.name, out, tt.want)
+		}
+	}
+}
+
+func TestWriteTSVCellsKeepsEncoding(t *testing.T) {
+	text := "姓\t名\t2026送\r\n\r\n佐藤\t花子\t\r\n"
+	sjis, err := japanese.ShiftJIS.NewEncoder().String(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "sjis.tsv")
+	if err := os.WriteFile(path, []byte(sjis), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeTSVCells(path, map[[2]int]string{{2, 2}: "○"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, enc, err := textenc.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc != textenc.CP932 {
+		t.Errorf("文字コード = %s, want %s", enc, textenc.CP932)
+	}
+	if want := "姓\t名\t2026送\r\n\r\n佐藤\t花子\t○\r\n"; got != want {
+		t.Errorf("内容 = %q, want %q", got, want)
+	}
+	if bak, err := os.ReadFile(path + ".bak"); err != nil || string(bak) != sjis {
+		t.Errorf(".bak が元のファイルと一致しません (%v)", err)
+	}
+}
@@ -0,0 +1,123 @@
+package sheets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"atena_printer/internal/config"
+)
+
+// checkboxTrue はチェックボックス列に書き込む値 (Sheets API の USER_ENTERED でチェックが入る)。
+const checkboxTrue = "TRUE"
+
+// statusValues は年ステータス列の値を有り・無しに判定する。
+type statusValues struct {
+	truthy map[string]bool // normalizeValue した値
+	falsy  map[string]bool
+	first  string // 書き込む値の既定 (true の先頭)
+
+	// unknown は true / false のどちらにも無かった値の出現位置 (警告用)。
+	unknown map[unknownKey][]int
+}
+
+type unknownKey struct {
+	column string
+	value  string
+}
+
+func newStatusValues(cfg config.StatusValues) *statusValues {
+	if len(cfg.True) == 0 {
+		cfg.True = config.DefaultStatusValues.True
+	}
+	if len(cfg.False) == 0 {
+		cfg.False = config.DefaultStatusValues.False
+	}
+	v := &statusValues{
+		truthy:  make(map[string]bool),
+		falsy:   make(map[string]bool),
+		first:   cfg.True[0],
+		unknown: make(map[unknownKey][]int),
+	}
+	for _, s := range cfg.True {
+		v.truthy[normalizeValue(s)] = true
+	}
+	for _, s := range cfg.False {
+		v.falsy[normalizeValue(s)] = true
+	}
+	return v
+}
+
+func normalizeValue(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// checked はセルの値を判定する。どちらにも無い値は有りとし、警告用に記録する。
+func (v *statusValues) checked(val, column string, row int) bool {
+	n := normalizeValue(val)
+	switch {
+	case n == "" || v.falsy[n]:
+		return false
+	case v.truthy[n]:
+		return true
+	}
+	key := unknownKey{column: column, value: val}
+	v.unknown[key] = append(v.unknown[key], row)
+	return true
+}
+
+// warnings は判定できなかった値の警告を列・値ごとにまとめて返す。
+func (v *statusValues) warnings() []string {
+	var keys []unknownKey
+	for k := range v.unknown {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].column != keys[j].column {
+			return keys[i].column < keys[j].column
+		}
+		return keys[i].value < keys[j].value
+	})
+
+	var msgs []string
+	for _, k := range keys {
+		rows := v.unknown[k]
+		where := fmt.Sprintf("%d行目", rows[0])
+		if len(rows) > 1 {
+			where += fmt.Sprintf(" ほか%d件", len(rows)-1)
+		}
+		msgs = append(msgs, fmt.Sprintf("列 %s の値 %q (%s) は status_values に無いため「有り」として扱います", k.column, k.value, where))
+	}
+	return msgs
+}
+
+// writeValue は列 col に書き込む「有り」の値を決める。
+// チェックボックスの列 (TRUE / FALSE が入っている) には TRUE を、それ以外は列で最も多く使われている値を書く。
+// 列が空の場合は同じ種類の他の年の列を参考にし、それも無ければ status_values.true の先頭を使う。
+func (v *statusValues) writeValue(values [][]string, col int, others []int) string {
+	for _, cols := range [][]int{{col}, others} {
+		count := make(map[string]int)
+		for _, row := range values[1:] {
+			for _, c := range cols {
+				val := getCell(row, c)
+				n := normalizeValue(val)
+				if n == "TRUE" || n == "FALSE" {
+					return checkboxTrue
+				}
+				if v.truthy[n] {
+					count[val]++
+				}
+			}
+		}
+		best, bestN := "", 0
+		for val, n := range count {
+			if n > bestN || (n == bestN && val < best) {
+				best, bestN = val, n
+			}
+		}
+		if best != "" {
+			return best
+		}
+	}
+	return v.first
+}
@@ -0,0 +1,60 @@
+package sheets
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/xlsx"
+)
+
+// xlsxBackend は Excel の .xlsx ファイルのシートを読み込む (読み取り専用)。
+// シートは sheet_name で選び、見つからない場合はエラーにする。
+// sheet_name が既定の「住所録」のままでその名前のシートが無い場合は、先頭のシートを読む。
+type xlsxBackend struct {
+	path      string
+	sheetName string
+}
+
+// NewXLSX は .xlsx ファイルを読み込む Client を作る。
+func NewXLSX(path, sheetName string) *Client {
+	return &Client{
+		backend:   &xlsxBackend{path: path, sheetName: sheetName},
+		sheetName: sheetName,
+	}
+}
+
+func (b *xlsxBackend) target() string {
+	return b.path
+}
+
+func (b *xlsxBackend) describe() string {
+	return fmt.Sprintf("Excel ファイル %s / %s", b.path, b.sheetName)
+}
+
+func (b *xlsxBackend) getValues(readRange string) ([][]string, error) {
+	wb, err := xlsx.Open(b.path)
+	if err != nil {
+		return nil, err
+	}
+	defer wb.Close()
+
+	name := b.sheetName
+	if name == config.DefaultSheetName && !slices.Contains(wb.SheetNames(), name) {
+		name = ""
+	}
+	values, err := wb.Rows(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.path, err)
+	}
+
+	if strings.HasSuffix(readRange, "!1:1") {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return [][]string{values[0]}, nil
+	}
+
+	return values, nil
+}
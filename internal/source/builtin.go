@@ -0,0 +1,95 @@
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/sheets"
+)
+
+// sheetsSource は sheets.Client をデータソースとして扱う。
+type sheetsSource struct {
+	*sheets.Client
+}
+
+// newSheetsSource は設定の列の対応 (columns)・年ステータスの値 (status_values)・
+// API の再試行 (api)・URL (endpoints) を適用した sheetsSource を作る。
+func newSheetsSource(c *sheets.Client, cfg *config.Config) sheetsSource {
+	c.SetColumns(cfg.Columns)
+	c.SetStatusValues(cfg.StatusValues)
+	c.SetWarnings(os.Stderr)
+	c.SetEndpoints(cfg.Endpoints)
+	c.SetRetryPolicy(sheets.RetryPolicy{
+		Timeout:    time.Duration(cfg.API.TimeoutSeconds) * time.Second,
+		MaxRetries: cfg.API.MaxRetries,
+		BaseDelay:  sheets.DefaultRetryPolicy.BaseDelay,
+		MaxDelay:   time.Duration(cfg.API.MaxBackoffSeconds) * time.Second,
+	})
+	return sheetsSource{c}
+}
+
+func (s sheetsSource) Capabilities() Capabilities {
+	return Capabilities{
+		Write:         s.Writable(),
+		AddColumns:    s.CanAddColumns(),
+		InsertColumns: s.CanInsertColumns(),
+	}
+}
+
+func init() {
+	Register("sheets", func(cfg *config.Config) (Source, error) {
+		if cfg.SpreadsheetID == "" {
+			return nil, fmt.Errorf("source: sheets には spreadsheet_id が必要です")
+		}
+		var c *sheets.Client
+		var err error
+		switch {
+		case cfg.TokenCommand != "" || cfg.CredentialsFile != "" || cfg.ApplicationDefault:
+			c, err = sheets.NewAPI(sheets.Credentials{
+				Command:            cfg.TokenCommand,
+				File:               cfg.CredentialsFile,
+				ApplicationDefault: cfg.ApplicationDefault,
+				Subject:            cfg.ImpersonateUser,
+			}, cfg.SpreadsheetID, cfg.SheetName)
+		case cfg.OAuthClientFile != "":
+			c, err = sheets.NewOAuth(cfg.OAuthClientFile, cfg.SpreadsheetID, cfg.SheetName)
+		default:
+			return nil, fmt.Errorf("source: sheets には credentials_file・oauth_client_file・application_default_credentials・token_command のいずれかが必要です")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return newSheetsSource(c, cfg), nil
+	})
+
+	Register("public", func(cfg *config.Config) (Source, error) {
+		if cfg.SpreadsheetID == "" {
+			return nil, fmt.Errorf("source: public には spreadsheet_id が必要です")
+		}
+		return newSheetsSource(sheets.NewPublicCSV(cfg.SpreadsheetID, cfg.SheetName), cfg), nil
+	})
+
+	Register("tsv", func(cfg *config.Config) (Source, error) {
+		if cfg.TSVFile == "" {
+			return nil, fmt.Errorf("source: tsv には tsv_file が必要です")
+		}
+		return newSheetsSource(sheets.NewTSV(cfg.TSVFile, cfg.SheetName), cfg), nil
+	})
+
+	Register("file", func(cfg *config.Config) (Source, error) {
+		if cfg.AddressFile == "" {
+			return nil, fmt.Errorf("source: file には address_file が必要です")
+		}
+		if isVCardFile(cfg.AddressFile) {
+			return vcardSource{cfg.AddressFile}, nil
+		}
+		if strings.EqualFold(filepath.Ext(cfg.AddressFile), ".xlsx") {
+			return newSheetsSource(sheets.NewXLSX(cfg.AddressFile, cfg.SheetName), cfg), nil
+		}
+		return newSheetsSource(sheets.NewFile(cfg.AddressFile, cfg.SheetName), cfg), nil
+	})
+}
@@ -0,0 +1,115 @@
+// Package source は住所録の読み込み元 (データソース) を設定から選んで開く。
+//
+// データソースは Source を実装し、年ステータスなどの書き込みに対応するものは
+// StatusWriter も実装する。コマンドはモードごとの分岐ではなく Capabilities で
+// 利用できる操作を判定する。新しいデータソースは Register で登録する。
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/journal"
+	"atena_printer/internal/model"
+)
+
+// Capabilities はデータソースが対応している操作。
+type Capabilities struct {
+	Write      bool // 年ステータス・ID を書き込める
+	AddColumns bool // 書き込み時に足りない列を追加できる
+
+	InsertColumns bool // new-year で年ステータス列を挿入できる
+}
+
+// Source は住所一覧と年ごとのステータスを読み込むデータソース。
+type Source interface {
+	ReadAddresses(year int) ([]model.Address, map[int]model.YearStatus, error)
+	ReadHistory() ([]model.Address, map[int]model.History, error)
+	Capabilities() Capabilities
+	Describe() string
+}
+
+// StatusWriter は年ステータスなどを書き込めるデータソース。
+type StatusWriter interface {
+	Source
+	SetJournal(j *journal.Journal, command string)
+	MarkSent(year int, targets []model.Address) error
+	MarkReceived(year int, targets []model.Address) error
+	AssignIDs(dryRun bool) (int, error)
+	Undo(op *journal.Operation) error
+}
+
+// YearColumnCreator は年ステータス列 (YYYY送/受/喪中) を追加できるデータソース。
+type YearColumnCreator interface {
+	PlanNewYear(year int) (*model.NewYearColumns, error)
+	NewYear(year int, copyFormat bool) (*model.NewYearColumns, error)
+}
+
+// AddressFiller は宛先の空の郵便番号・住所を埋められるデータソース (complete で使う)。
+type AddressFiller interface {
+	FillAddresses(fills []model.AddressFill) error
+}
+
+// Factory は設定からデータソースを作る。
+type Factory func(cfg *config.Config) (Source, error)
+
+var registry = make(map[string]Factory)
+
+// Register はデータソースを名前で登録する。名前は設定の source で指定する。
+func Register(name string, f Factory) {
+	if _, dup := registry[name]; dup {
+		panic("source: " + name + " は登録済みです")
+	}
+	registry[name] = f
+}
+
+// Names は登録されているデータソース名を返す。
+func Names() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Kind は設定で使うデータソース名を返す。source が空の場合は
+// sqlite_file → address_file → tsv_file → Google の認証情報 (credentials_file など) → 公開シート の順に判定する。
+func Kind(cfg *config.Config) string {
+	switch {
+	case cfg.Source != "":
+		return cfg.Source
+	case cfg.SQLiteFile != "":
+		return "sqlite"
+	case cfg.AddressFile != "":
+		return "file"
+	case cfg.TSVFile != "":
+		return "tsv"
+	case cfg.CredentialsFile != "" || cfg.OAuthClientFile != "" || cfg.ApplicationDefault || cfg.TokenCommand != "":
+		return "sheets"
+	}
+	return "public"
+}
+
+// Open は設定に対応するデータソースを開く。
+func Open(cfg *config.Config) (Source, error) {
+	kind := Kind(cfg)
+	f, ok := registry[kind]
+	if !ok {
+		return nil, fmt.Errorf("不明な source: %s (%s のいずれかを指定してください)", kind, strings.Join(Names(), ", "))
+	}
+	return f(cfg)
+}
+
+// Writer は src が書き込みに対応していれば StatusWriter として返す。
+// 対応していない場合は command を使えない旨のエラーを返す。
+func Writer(src Source, command string) (StatusWriter, error) {
+	w, ok := src.(StatusWriter)
+	if !ok || !src.Capabilities().Write {
+		return nil, fmt.Errorf("%s は読み取り専用のデータソース (%s) では使えません。credentials_file・oauth_client_file・tsv_file・sqlite_file のいずれかを設定してください",
+			command, src.Describe())
+	}
+	return w, nil
+}
@@ -0,0 +1,247 @@
+package source
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"atena_printer/internal/addressdb"
+	"atena_printer/internal/config"
+	"atena_printer/internal/journal"
+	"atena_printer/internal/model"
+)
+
+// sqliteSource は addressdb の住所録をデータソースとして扱う。
+// 年ステータスの書き込みはシートと同じく journal に記録し、undo で戻せる。
+// journal の Range は年ステータスでは「ID/年/種類」(例: 1a2b3c4d/2026/sent) で値は "○" か空文字、
+// 住所 (complete) では「ID/項目」(例: 1a2b3c4d/postal_code) で値はその項目の値。
+type sqliteSource struct {
+	db *addressdb.DB
+
+	journal *journal.Journal
+	command string
+}
+
+func (s *sqliteSource) ReadAddresses(year int) ([]model.Address, map[int]model.YearStatus, error) {
+	addresses, histories, err := s.db.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	statuses := make(map[int]model.YearStatus, len(addresses))
+	for _, addr := range addresses {
+		statuses[addr.Row] = histories[addr.Row][year]
+	}
+	return addresses, statuses, nil
+}
+
+func (s *sqliteSource) ReadHistory() ([]model.Address, map[int]model.History, error) {
+	return s.db.List()
+}
+
+func (s *sqliteSource) Capabilities() Capabilities {
+	return Capabilities{Write: true}
+}
+
+func (s *sqliteSource) Describe() string {
+	return "SQLite " + s.db.Path()
+}
+
+func (s *sqliteSource) target() string {
+	return "sqlite:" + s.db.Path()
+}
+
+func (s *sqliteSource) SetJournal(j *journal.Journal, command string) {
+	s.journal = j
+	s.command = command
+}
+
+func (s *sqliteSource) MarkSent(year int, targets []model.Address) error {
+	return s.mark(year, addressdb.KindSent, targets)
+}
+
+func (s *sqliteSource) MarkReceived(year int, targets []model.Address) error {
+	return s.mark(year, addressdb.KindReceived, targets)
+}
+
+func (s *sqliteSource) mark(year int, kind string, targets []model.Address) error {
+	var changes []journal.Change
+	for _, t := range targets {
+		if t.ID == "" {
+			return fmt.Errorf("%s %s: ID がありません", t.FamilyName, t.GivenName)
+		}
+		old, err := s.db.Status(t.ID, year, kind)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, journal.Change{
+			Range: fmt.Sprintf("%s/%d/%s", t.ID, year, kind),
+			Old:   statusMark(old),
+			New:   "○",
+		})
+	}
+	return s.write(changes, "")
+}
+
+// AssignIDs は何もしない (SQLite の宛先には追加時に必ず ID が割り当てられる)。
+func (s *sqliteSource) AssignIDs(dryRun bool) (int, error) {
+	return 0, nil
+}
+
+// FillAddresses は宛先の空の郵便番号・住所1・住所2に値を書き込む。
+// 読み込み後に値が入った場合は上書きを避けるため何も書き込まずにエラーにする。
+func (s *sqliteSource) FillAddresses(fills []model.AddressFill) error {
+	var changes []journal.Change
+	var conflicts []string
+	for _, f := range fills {
+		t := f.Target
+		if t.ID == "" {
+			return fmt.Errorf("%s %s: ID がありません", t.FamilyName, t.GivenName)
+		}
+		for _, field := range []struct{ name, value string }{
+			{addressdb.FieldPostalCode, f.PostalCode},
+			{addressdb.FieldAddress1, f.Address1},
+			{addressdb.FieldAddress2, f.Address2},
+		} {
+			if field.value == "" {
+				continue
+			}
+			r := t.ID + "/" + field.name
+			current, err := s.db.AddressField(t.ID, field.name)
+			if err != nil {
+				return err
+			}
+			if current != "" {
+				conflicts = append(conflicts, fmt.Sprintf("  %s (%s %s): 空欄だった項目に %q が入っています", r, t.FamilyName, t.GivenName, current))
+				continue
+			}
+			changes = append(changes, journal.Change{Range: r, New: field.value})
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("読み込み後に変更された項目があるため書き込みません。再実行してください:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return s.write(changes, "")
+}
+
+func (s *sqliteSource) Undo(op *journal.Operation) error {
+	if op.Target != s.target() {
+		return fmt.Errorf("操作 %s の書き込み先 (%s) が現在の設定 (%s) と異なります", op.ID, op.Target, s.target())
+	}
+
+	var changes []journal.Change
+	var conflicts []string
+	for _, ch := range op.Changes {
+		current, err := s.value(ch.Range)
+		if err != nil {
+			return err
+		}
+		if current != ch.New {
+			conflicts = append(conflicts, fmt.Sprintf("  %s: 書き込んだ値 %q が現在は %q です", ch.Range, ch.New, current))
+			continue
+		}
+		changes = append(changes, journal.Change{Range: ch.Range, Old: ch.New, New: ch.Old})
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("書き込み後に変更された値があるため取り消せません:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return s.write(changes, op.ID)
+}
+
+// value は journal の Range が指す現在の値を返す。
+func (s *sqliteSource) value(r string) (string, error) {
+	if uid, field, ok := parseFieldRange(r); ok {
+		return s.db.AddressField(uid, field)
+	}
+	uid, year, kind, err := parseStatusRange(r)
+	if err != nil {
+		return "", err
+	}
+	current, err := s.db.Status(uid, year, kind)
+	if err != nil {
+		return "", err
+	}
+	return statusMark(current), nil
+}
+
+// write は年ステータス・住所を書き換える。journal が設定されていれば、書き込みの前に操作を記録する
+// (記録の後で書き込みに失敗した場合は、その操作を取り消し済みとして記録する)。
+func (s *sqliteSource) write(changes []journal.Change, undoes string) error {
+	var updates []addressdb.StatusChange
+	var fields []addressdb.AddressChange
+	for _, ch := range changes {
+		if uid, field, ok := parseFieldRange(ch.Range); ok {
+			fields = append(fields, addressdb.AddressChange{UID: uid, Field: field, Value: ch.New})
+			continue
+		}
+		uid, year, kind, err := parseStatusRange(ch.Range)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, addressdb.StatusChange{UID: uid, Year: year, Kind: kind, Value: ch.New != ""})
+	}
+
+	var op *journal.Operation
+	if s.journal != nil {
+		op = &journal.Operation{
+			Command: s.command,
+			Target:  s.target(),
+			Undoes:  undoes,
+			Changes: changes,
+		}
+		if err := s.journal.Append(op); err != nil {
+			return fmt.Errorf("書き込み記録の保存に失敗したため書き込みません: %w", err)
+		}
+	}
+
+	err := s.db.SetStatuses(updates)
+	if err == nil && len(fields) > 0 {
+		err = s.db.SetAddressFields(fields)
+	}
+	if err != nil && op != nil {
+		if aerr := s.journal.Abort(op); aerr != nil {
+			return fmt.Errorf("%w (書き込み記録 %s を取り消し済みにできませんでした: %v)", err, op.ID, aerr)
+		}
+	}
+	return err
+}
+
+// parseFieldRange は住所の項目の Range (「ID/項目」) を解析する。年ステータスの Range なら ok は false。
+func parseFieldRange(r string) (uid, field string, ok bool) {
+	uid, field, ok = strings.Cut(r, "/")
+	if !ok || strings.Contains(field, "/") {
+		return "", "", false
+	}
+	return uid, field, true
+}
+
+func parseStatusRange(r string) (uid string, year int, kind string, err error) {
+	parts := strings.Split(r, "/")
+	if len(parts) == 3 {
+		if year, err = strconv.Atoi(parts[1]); err == nil {
+			return parts[0], year, parts[2], nil
+		}
+	}
+	return "", 0, "", fmt.Errorf("書き込み記録の範囲を解析できません: %s", r)
+}
+
+func statusMark(v bool) string {
+	if v {
+		return "○"
+	}
+	return ""
+}
+
+func init() {
+	Register("sqlite", func(cfg *config.Config) (Source, error) {
+		if cfg.SQLiteFile == "" {
+			return nil, fmt.Errorf("source: sqlite には sqlite_file が必要です")
+		}
+		db, err := addressdb.Open(cfg.SQLiteFile)
+		if err != nil {
+			return nil, err
+		}
+		return &sqliteSource{db: db}, nil
+	})
+}
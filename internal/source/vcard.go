@@ -0,0 +1,55 @@
+package source
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/model"
+	"atena_printer/internal/vcard"
+)
+
+// vcardSource は .vcf ファイルを読み込み専用のデータソースとして扱う。
+// vCard には年ごとのステータスが無いため、履歴は常に空になる。
+type vcardSource struct {
+	path string
+}
+
+func (s vcardSource) ReadAddresses(year int) ([]model.Address, map[int]model.YearStatus, error) {
+	addresses, err := vcard.ReadFile(s.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return addresses, make(map[int]model.YearStatus), nil
+}
+
+func (s vcardSource) ReadHistory() ([]model.Address, map[int]model.History, error) {
+	addresses, err := vcard.ReadFile(s.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return addresses, make(map[int]model.History), nil
+}
+
+func (s vcardSource) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+func (s vcardSource) Describe() string {
+	return fmt.Sprintf("vCard ファイル %s", s.path)
+}
+
+func isVCardFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".vcf" || ext == ".vcard"
+}
+
+func init() {
+	Register("vcard", func(cfg *config.Config) (Source, error) {
+		if cfg.AddressFile == "" {
+			return nil, fmt.Errorf("source: vcard には address_file が必要です")
+		}
+		return vcardSource{cfg.AddressFile}, nil
+	})
+}
@@ -0,0 +1,192 @@
+package stats
+
+import (
+	"sort"
+
+	"atena_printer/internal/model"
+)
+
+// YearCount は1年分の集計。
+type YearCount struct {
+	Year     int `json:"year"`
+	Sent     int `json:"sent"`
+	Received int `json:"received"`
+	Both     int `json:"both"`     // 送受信とも
+	Mourning int `json:"mourning"` // 喪中
+}
+
+// 集計期間内のやり取りの区分。
+const (
+	KindMutual       = "mutual"        // 双方から送っている
+	KindSentOnly     = "sent_only"     // こちらからだけ送っている
+	KindReceivedOnly = "received_only" // 相手からだけ届いている
+	KindNone         = "none"          // どちらからも送っていない
+)
+
+// Person は1件分の集計。Sent / Received は集計期間内の年数。
+type Person struct {
+	Row           int    `json:"row"`
+	ID            string `json:"id,omitempty"`
+	FamilyName    string `json:"family_name"`
+	GivenName     string `json:"given_name"`
+	Sent          int    `json:"sent"`
+	Received      int    `json:"received"`
+	SendStreak    int    `json:"send_streak"`    // 直近から連続して送った年数
+	ReceiveStreak int    `json:"receive_streak"` // 直近から連続して届いた年数
+	LastSent      int    `json:"last_sent,omitempty"`
+	LastReceived  int    `json:"last_received,omitempty"`
+	Kind          string `json:"kind"`
+	// Removal は集計期間内に一度も届いておらず、喪中でもない (削除候補)。
+	Removal bool `json:"removal"`
+}
+
+// Report は stats コマンドの集計結果。
+type Report struct {
+	From   int         `json:"from"` // 集計期間 (この年から)
+	To     int         `json:"to"`   // 集計期間 (この年まで)
+	Years  []YearCount `json:"years"`
+	People []Person    `json:"people"`
+}
+
+// Build は until 年までの windowYears 年間を集計期間として集計する。
+// until 年にまだ記録が無い場合 (年の列を追加しただけの場合など) は、記録のある最後の年までを
+// 集計期間とし、連続年数もその年から数える。年ごとの集計はその年までのすべての年を対象にする。
+func Build(addresses []model.Address, histories map[int]model.History, until, windowYears int) *Report {
+	until = lastRecordedYear(histories, until)
+	years := collectYears(histories, until)
+	from := until - windowYears + 1
+
+	r := &Report{From: from, To: until}
+
+	counts := make(map[int]*YearCount)
+	for _, y := range years {
+		counts[y] = &YearCount{Year: y}
+	}
+
+	for _, addr := range addresses {
+		h := histories[addr.Row]
+		p := Person{
+			Row:        addr.Row,
+			ID:         addr.ID,
+			FamilyName: addr.FamilyName,
+			GivenName:  addr.GivenName,
+		}
+
+		mourning := false
+		for _, y := range years {
+			st := h[y]
+			c := counts[y]
+			if st.Sent {
+				c.Sent++
+			}
+			if st.Received {
+				c.Received++
+			}
+			if st.Sent && st.Received {
+				c.Both++
+			}
+			if st.Mourning {
+				c.Mourning++
+			}
+
+			if st.Sent && y > p.LastSent {
+				p.LastSent = y
+			}
+			if st.Received && y > p.LastReceived {
+				p.LastReceived = y
+			}
+			if y < from {
+				continue
+			}
+			if st.Sent {
+				p.Sent++
+			}
+			if st.Received {
+				p.Received++
+			}
+			if st.Mourning {
+				mourning = true
+			}
+		}
+
+		p.SendStreak = streak(h, years, func(st model.YearStatus) bool { return st.Sent })
+		p.ReceiveStreak = streak(h, years, func(st model.YearStatus) bool { return st.Received })
+
+		switch {
+		case p.Sent > 0 && p.Received > 0:
+			p.Kind = KindMutual
+		case p.Sent > 0:
+			p.Kind = KindSentOnly
+		case p.Received > 0:
+			p.Kind = KindReceivedOnly
+		default:
+			p.Kind = KindNone
+		}
+		p.Removal = p.Received == 0 && !mourning
+
+		r.People = append(r.People, p)
+	}
+
+	for _, y := range years {
+		r.Years = append(r.Years, *counts[y])
+	}
+	return r
+}
+
+// Filter は cond を満たす宛先だけを返す。
+func (r *Report) Filter(cond func(Person) bool) []Person {
+	var result []Person
+	for _, p := range r.People {
+		if cond(p) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// lastRecordedYear は until 年以前で 送・受・喪中 のいずれかの記録がある最後の年を返す。
+// 記録が1件も無ければ until を返す。
+func lastRecordedYear(histories map[int]model.History, until int) int {
+	last := 0
+	for _, h := range histories {
+		for y, st := range h {
+			if y <= until && y > last && (st.Sent || st.Received || st.Mourning) {
+				last = y
+			}
+		}
+	}
+	if last == 0 {
+		return until
+	}
+	return last
+}
+
+// collectYears はシート上にある until 年以前の年を昇順で返す。
+func collectYears(histories map[int]model.History, until int) []int {
+	seen := make(map[int]bool)
+	for _, h := range histories {
+		for y := range h {
+			if y <= until {
+				seen[y] = true
+			}
+		}
+	}
+	var years []int
+	for y := range seen {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	return years
+}
+
+// streak は最新の年から遡って cond を満たす年が何年続いているかを返す。
+func streak(h model.History, years []int, cond func(model.YearStatus) bool) int {
+	n := 0
+	for i := len(years) - 1; i >= 0; i-- {
+		if !cond(h[years[i]]) {
+			break
+		}
+		n++
+	}
+	return n
+}
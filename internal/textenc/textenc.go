@@ -0,0 +1,141 @@
+// Package textenc は Excel などが出力した住所録ファイルの文字コードと区切り文字を判定する。
+package textenc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// Encoding は判定した文字コード。
+type Encoding string
+
+const (
+	UTF8       Encoding = "UTF-8"
+	UTF8BOM    Encoding = "UTF-8 (BOM付き)"
+	CP932      Encoding = "Shift_JIS (CP932)"
+	UTF16LE    Encoding = "UTF-16LE"
+	UTF16LEBOM Encoding = "UTF-16LE (BOM付き)"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+)
+
+// Decode はファイルの内容の文字コードを判定し、UTF-8 の文字列に変換する。
+// BOM があればそれに従い、無ければ UTF-16LE らしさ → UTF-8 として正しいか → CP932 の順に判定する。
+func Decode(data []byte) (string, Encoding, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return string(data[len(bomUTF8):]), UTF8BOM, nil
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeUTF16LE(data[len(bomUTF16LE):]), UTF16LEBOM, nil
+	case looksUTF16LE(data):
+		return decodeUTF16LE(data), UTF16LE, nil
+	case utf8.Valid(data):
+		return string(data), UTF8, nil
+	}
+
+	// Shift_JIS として読めないバイトは U+FFFD になる (デコーダはエラーを返さない) ので、その数で判定する
+	out, err := japanese.ShiftJIS.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", "", fmt.Errorf("文字コードを判定できません (UTF-8 / Shift_JIS / UTF-16LE のいずれでもありません): %w", err)
+	}
+	text := string(out)
+	if bad, total := strings.Count(text, string(utf8.RuneError)), utf8.RuneCountInString(text); bad > 0 && bad*maxInvalidRatio > total {
+		return "", "", fmt.Errorf("文字コードを判定できません (UTF-8 / Shift_JIS / UTF-16LE のいずれでもありません。Shift_JIS として読めない文字が %d 文字あります)", bad)
+	}
+	return text, CP932, nil
+}
+
+// maxInvalidRatio は Shift_JIS とみなす読めない文字の割合の上限 (1/maxInvalidRatio)。
+// 機種依存文字などが少し混ざっている程度なら Shift_JIS として読み込む。
+const maxInvalidRatio = 50
+
+// Encode は UTF-8 の text を enc の文字コードに戻す (Decode したファイルを書き戻す場合に使う)。
+func Encode(text []byte, enc Encoding) ([]byte, error) {
+	switch enc {
+	case UTF8, "":
+		return text, nil
+	case UTF8BOM:
+		return append(append([]byte{}, bomUTF8...), text...), nil
+	case CP932:
+		out, err := japanese.ShiftJIS.NewEncoder().Bytes(text)
+		if err != nil {
+			return nil, fmt.Errorf("Shift_JIS で表せない文字があります: %w", err)
+		}
+		return out, nil
+	case UTF16LE, UTF16LEBOM:
+		var out []byte
+		if enc == UTF16LEBOM {
+			out = append(out, bomUTF16LE...)
+		}
+		for _, u := range utf16.Encode([]rune(string(text))) {
+			out = append(out, byte(u), byte(u>>8))
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("文字コード %s には書き込めません", enc)
+}
+
+// looksUTF16LE は BOM 無しの UTF-16LE かどうかを推定する。
+// 住所録は ASCII の区切り文字や数字を多く含むため、奇数バイト目に 0x00 が多ければ UTF-16LE とみなす。
+func looksUTF16LE(data []byte) bool {
+	n := min(len(data), 1024) &^ 1
+	if n < 4 {
+		return false
+	}
+	zeros := 0
+	for i := 1; i < n; i += 2 {
+		if data[i] == 0 {
+			zeros++
+		}
+	}
+	// 漢字・かなは上位バイトが 0x00 にならないため、奇数バイトの4分の1以上が 0x00 なら UTF-16LE とみなす
+	return zeros*4 >= n/2
+}
+
+func decodeUTF16LE(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
+
+// DetectDelimiter は先頭行 (ヘッダ) の引用符外にあるタブとカンマを数え、区切り文字を返す。
+func DetectDelimiter(text string) rune {
+	tabs, commas := 0, 0
+	inQuotes := false
+loop:
+	for _, r := range text {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case r == '\n':
+			break loop
+		case r == '\t':
+			tabs++
+		case r == ',':
+			commas++
+		}
+	}
+	if tabs > 0 && tabs >= commas {
+		return '\t'
+	}
+	return ','
+}
+
+// DelimiterName は区切り文字の表示名を返す。
+func DelimiterName(d rune) string {
+	if d == '\t' {
+		return "TSV"
+	}
+	return "CSV"
+}
@@ -0,0 +1,419 @@
+// Package vcard は vCard 3.0 / 4.0 (.vcf) と model.Address の相互変換を行う。
+//
+// 読み込み時の対応:
+//
+//	N                      姓・名
+//	X-PHONETIC-LAST-NAME   よみ (姓)
+//	X-PHONETIC-FIRST-NAME  よみ (名)
+//	ADR                    郵便番号・住所 (地域=都道府県、市区町村、番地。番地の2行目以降と拡張住所は住所2)
+//	CATEGORIES             タグ
+//	X-ATENA-JOINT-NAMES    連名 (このツールの書き出し用の拡張)
+//	X-ATENA-HONORIFIC      敬称 (同上)
+package vcard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"atena_printer/internal/model"
+	"atena_printer/internal/postal"
+)
+
+// Card は vCard 1件分のプロパティ。
+type Card struct {
+	Props []Property
+}
+
+// Property は vCard の1行 (NAME;PARAM=...:VALUE)。
+type Property struct {
+	Name   string              // 大文字 (グループ名は除く)
+	Params map[string][]string // 名前は大文字
+	Value  string              // エスケープを残した値
+}
+
+// Get は name の最初のプロパティを返す。
+func (c *Card) Get(name string) (Property, bool) {
+	for _, p := range c.Props {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// HasType は TYPE パラメータに t (大文字小文字を区別しない) を含むかどうかを返す。
+func (p Property) HasType(t string) bool {
+	for _, v := range p.Params["TYPE"] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(s, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Text はエスケープを解除した値を返す。
+func (p Property) Text() string {
+	return unescape(p.Value)
+}
+
+// Fields は ; 区切りの構造化された値を分割し、エスケープを解除して返す。
+func (p Property) Fields() []string {
+	var fields []string
+	for _, f := range splitUnescaped(p.Value, ';') {
+		fields = append(fields, unescape(f))
+	}
+	return fields
+}
+
+// List は , 区切りの値を分割し、エスケープを解除して返す。
+func (p Property) List() []string {
+	var items []string
+	for _, f := range splitUnescaped(p.Value, ',') {
+		if f = strings.TrimSpace(unescape(f)); f != "" {
+			items = append(items, f)
+		}
+	}
+	return items
+}
+
+// Parse は .vcf の内容を読み込む。
+func Parse(r io.Reader) ([]Card, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []Card
+	var cur *Card
+	for n, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("vCard の %d 行目: %w", n+1, err)
+		}
+		switch {
+		case prop.Name == "BEGIN" && strings.EqualFold(prop.Value, "VCARD"):
+			cur = &Card{}
+		case prop.Name == "END" && strings.EqualFold(prop.Value, "VCARD"):
+			if cur != nil {
+				cards = append(cards, *cur)
+			}
+			cur = nil
+		case cur != nil:
+			cur.Props = append(cur.Props, prop)
+		}
+	}
+	return cards, nil
+}
+
+// ReadFile は .vcf ファイルを読み込み、住所録の形式に変換する。
+// 行番号 (Row) はファイル内の順番 (1 始まり) にする。
+func ReadFile(path string) ([]model.Address, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("vCard ファイルを開けません: %w", err)
+	}
+	defer f.Close()
+
+	cards, err := Parse(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []model.Address
+	for i, card := range cards {
+		addr, ok := ToAddress(card)
+		if !ok {
+			continue
+		}
+		addr.Row = i + 1
+		addresses = append(addresses, addr)
+	}
+	return addresses, nil
+}
+
+// ToAddress は vCard を宛先に変換する。姓が無い (N も FN も無い) 場合は false。
+func ToAddress(card Card) (model.Address, bool) {
+	var addr model.Address
+
+	if n, ok := card.Get("N"); ok {
+		f := padFields(n.Fields(), 5)
+		addr.FamilyName = strings.TrimSpace(f[0])
+		addr.GivenName = strings.TrimSpace(f[1])
+	}
+	if addr.FamilyName == "" {
+		// N が無い場合は FN を姓名の区切り (空白) で分ける
+		if fn, ok := card.Get("FN"); ok {
+			parts := strings.Fields(strings.ReplaceAll(fn.Text(), "　", " "))
+			if len(parts) > 0 {
+				addr.FamilyName = parts[0]
+				addr.GivenName = strings.Join(parts[1:], "")
+			}
+		}
+	}
+	if addr.FamilyName == "" {
+		return addr, false
+	}
+
+	var reading []string
+	for _, name := range []string{"X-PHONETIC-LAST-NAME", "X-PHONETIC-FIRST-NAME"} {
+		if p, ok := card.Get(name); ok && p.Text() != "" {
+			reading = append(reading, p.Text())
+		}
+	}
+	addr.Reading = strings.Join(reading, " ")
+
+	if adr, ok := preferredADR(card); ok {
+		// pobox;ext;street;locality;region;code;country
+		f := padFields(adr.Fields(), 7)
+		street := strings.Split(strings.ReplaceAll(f[2], "\r\n", "\n"), "\n")
+		addr.Address1 = f[4] + f[3] + strings.TrimSpace(street[0])
+		var rest []string
+		for _, s := range append(street[1:], f[1]) {
+			if s = strings.TrimSpace(s); s != "" {
+				rest = append(rest, s)
+			}
+		}
+		addr.Address2 = strings.Join(rest, " ")
+		addr.PostalCode = postal.Normalize(f[5])
+	}
+
+	for _, p := range card.Props {
+		if p.Name == "CATEGORIES" {
+			addr.Tags = append(addr.Tags, p.List()...)
+		}
+	}
+	if p, ok := card.Get("X-ATENA-JOINT-NAMES"); ok {
+		addr.JointNames = p.List()
+	}
+	if p, ok := card.Get("X-ATENA-HONORIFIC"); ok {
+		addr.Honorific = p.Text()
+	}
+	if addr.Honorific == "" {
+		addr.Honorific = "様"
+	}
+	if p, ok := card.Get("UID"); ok {
+		addr.ID = p.Text()
+	}
+
+	return addr, true
+}
+
+// preferredADR は自宅 (TYPE=HOME) の住所を優先して返す。
+func preferredADR(card Card) (Property, bool) {
+	var first *Property
+	for i, p := range card.Props {
+		if p.Name != "ADR" {
+			continue
+		}
+		if p.HasType("HOME") {
+			return p, true
+		}
+		if first == nil {
+			first = &card.Props[i]
+		}
+	}
+	if first == nil {
+		return Property{}, false
+	}
+	return *first, true
+}
+
+// formattedName は FN に書く表示名 (空でない姓・名を空白で区切る)。
+func formattedName(addr model.Address) string {
+	var parts []string
+	for _, s := range []string{addr.FamilyName, addr.GivenName} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Write は宛先を vCard 3.0 として書き出す。
+func Write(w io.Writer, addresses []model.Address) error {
+	bw := bufio.NewWriter(w)
+	for _, addr := range addresses {
+		lines := []string{
+			"BEGIN:VCARD",
+			"VERSION:3.0",
+			"FN:" + escape(formattedName(addr)),
+			"N:" + escape(addr.FamilyName) + ";" + escape(addr.GivenName) + ";;;",
+		}
+		if addr.ID != "" {
+			lines = append(lines, "UID:"+escape(addr.ID))
+		}
+		if addr.Reading != "" {
+			parts := strings.SplitN(strings.ReplaceAll(addr.Reading, "　", " "), " ", 2)
+			lines = append(lines, "X-PHONETIC-LAST-NAME:"+escape(parts[0]))
+			if len(parts) == 2 {
+				lines = append(lines, "X-PHONETIC-FIRST-NAME:"+escape(strings.TrimSpace(parts[1])))
+			}
+		}
+		if addr.Address1 != "" || addr.PostalCode != "" {
+			region := model.Prefecture(addr.Address1)
+			street := strings.TrimPrefix(addr.Address1, region)
+			if addr.Address2 != "" {
+				street += "\n" + addr.Address2
+			}
+			postal := addr.PostalCode
+			if len(postal) == 7 {
+				postal = postal[:3] + "-" + postal[3:]
+			}
+			lines = append(lines, "ADR;TYPE=HOME:;;"+escape(street)+";;"+escape(region)+";"+escape(postal)+";"+escape("日本"))
+		}
+		if len(addr.Tags) > 0 {
+			lines = append(lines, "CATEGORIES:"+escapeList(addr.Tags))
+		}
+		if len(addr.JointNames) > 0 {
+			lines = append(lines, "X-ATENA-JOINT-NAMES:"+escapeList(addr.JointNames))
+		}
+		if addr.Honorific != "" && addr.Honorific != "様" {
+			lines = append(lines, "X-ATENA-HONORIFIC:"+escape(addr.Honorific))
+		}
+		lines = append(lines, "END:VCARD")
+
+		for _, l := range lines {
+			if _, err := bw.WriteString(fold(l) + "\r\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// --- 行の処理 ---
+
+// unfold は折り返された行 (CRLF の直後が空白・タブ) を1行に戻す。
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	var lines []string
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("vCard の読み込みに失敗: %w", err)
+	}
+	return lines, nil
+}
+
+// fold は 75 バイトを超える行を、UTF-8 の文字の途中で切らないように折り返す。
+func fold(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
+
+func parseLine(line string) (Property, error) {
+	colon := -1
+	inQuote := false
+	for i, r := range line {
+		if r == '"' {
+			inQuote = !inQuote
+		}
+		if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return Property{}, fmt.Errorf("':' がありません: %s", line)
+	}
+
+	head := splitUnescaped(line[:colon], ';')
+	name := strings.ToUpper(head[0])
+	if dot := strings.LastIndex(name, "."); dot >= 0 { // item1.ADR のようなグループ名
+		name = name[dot+1:]
+	}
+	prop := Property{Name: name, Params: make(map[string][]string), Value: line[colon+1:]}
+	for _, p := range head[1:] {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok { // vCard 2.1 形式の TYPE 省略 (例: ADR;HOME)
+			k, v = "TYPE", p
+		}
+		k = strings.ToUpper(k)
+		prop.Params[k] = append(prop.Params[k], strings.Trim(v, `"`))
+	}
+	return prop, nil
+}
+
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func escapeList(items []string) string {
+	var escaped []string
+	for _, it := range items {
+		escaped = append(escaped, escape(it))
+	}
+	return strings.Join(escaped, ",")
+}
+
+func padFields(f []string, n int) []string {
+	for len(f) < n {
+		f = append(f, "")
+	}
+	return f
+}
@@ -0,0 +1,107 @@
+package vcard
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"atena_printer/internal/model"
+)
+
+func TestToAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		vcf  string
+		want model.Address
+		ok   bool
+	}{
+		{
+			name: "N と自宅の ADR",
+			vcf: "BEGIN:VCARD\r\nVERSION:3.0\r\nN:佐藤;花子;;;\r\nFN:佐藤 花子\r\n" +
+				"X-PHONETIC-LAST-NAME:さとう\r\nX-PHONETIC-FIRST-NAME:はなこ\r\n" +
+				"ADR;TYPE=WORK:;;大手町1-1;千代田区;東京都;100-0004;日本\r\n" +
+				"ADR;TYPE=HOME:;101号室;神宮前3-1-2\\nサンプルマンション;渋谷区;東京都;〒150-0001;日本\r\n" +
+				"CATEGORIES:家族,友人\r\nUID:a1\r\nEND:VCARD\r\n",
+			want: model.Address{
+				ID: "a1", FamilyName: "佐藤", GivenName: "花子", Reading: "さとう はなこ",
+				Honorific: "様", PostalCode: "1500001",
+				Address1: "東京都渋谷区神宮前3-1-2", Address2: "サンプルマンション 101号室",
+				Tags: []string{"家族", "友人"},
+			},
+			ok: true,
+		},
+		{
+			name: "N が無ければ FN を分ける・折り返し行",
+			vcf: "BEGIN:VCARD\nVERSION:4.0\nFN:鈴木　一郎\nADR:;;梅田1-2-3;大阪市北区;大阪府;5300001;\n" +
+				"X-ATENA-JOINT-NAMES:花子,次郎\nX-ATENA-HONORIFIC:先\n 生\nEND:VCARD\n",
+			want: model.Address{
+				FamilyName: "鈴木", GivenName: "一郎", Honorific: "先生", PostalCode: "5300001",
+				Address1: "大阪府大阪市北区梅田1-2-3", JointNames: []string{"花子", "次郎"},
+			},
+			ok: true,
+		},
+		{
+			name: "名前が無い",
+			vcf:  "BEGIN:VCARD\nVERSION:3.0\nORG:会社\nEND:VCARD\n",
+			ok:   false,
+		},
+	}
+	for _, tt := range tests {
+		cards, err := Parse(strings.NewReader(tt.vcf))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(cards) != 1 {
+			t.Errorf("%s: %d件, want 1件", tt.name, len(cards))
+			continue
+		}
+		got, ok := ToAddress(cards[0])
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got  %+v\n want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	addresses := []model.Address{
+		{
+			ID: "a1", FamilyName: "高橋", GivenName: "美咲", Reading: "たかはし みさき",
+			JointNames: []string{"太郎", "次郎"}, Honorific: "先生", PostalCode: "9800001",
+			Address1: "宮城県仙台市青葉区中央1-1", Address2: "テストビル8F",
+			Tags: []string{"仕事", "a,b"},
+		},
+		{FamilyName: "株式会社サンプル", Honorific: "御中"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, addresses); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "FN:株式会社サンプル \r\n") {
+		t.Errorf("名が空の FN の末尾に空白があります:\n%s", buf.String())
+	}
+
+	cards, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != len(addresses) {
+		t.Fatalf("%d件, want %d件", len(cards), len(addresses))
+	}
+	for i, card := range cards {
+		got, ok := ToAddress(card)
+		if !ok {
+			t.Errorf("%d件目を読み込めません", i+1)
+			continue
+		}
+		if !reflect.DeepEqual(got, addresses[i]) {
+			t.Errorf("%d件目:\n got  %+v\n want %+v", i+1, got, addresses[i])
+		}
+	}
+}
@@ -0,0 +1,369 @@
+// Package xlsx は Excel の .xlsx ファイルからシートのセル値を文字列として読み込む。
+//
+// 対応しているのは住所録の読み込みに必要な範囲 (共有文字列・インライン文字列・数値・真偽値) で、
+// 数値は表示形式が「0000000」「000-0000」のようにゼロ埋めの場合、先頭の 0 を補った文字列にする。
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Workbook は開いた .xlsx ファイル。
+type Workbook struct {
+	zr      *zip.ReadCloser
+	sheets  []sheetRef
+	strings []string
+	styles  []string // セルのスタイル番号 → 数値の表示形式 (ゼロ埋め判定用)
+}
+
+type sheetRef struct {
+	name string
+	path string // zip 内のパス
+}
+
+// Open は .xlsx ファイルを開く。使い終わったら Close する。
+func Open(filename string) (*Workbook, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("xlsx ファイルを開けません: %w", err)
+	}
+	wb := &Workbook{zr: zr}
+	if err := wb.load(); err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return wb, nil
+}
+
+func (wb *Workbook) Close() error {
+	return wb.zr.Close()
+}
+
+// SheetNames はブック内のシート名を順に返す。
+func (wb *Workbook) SheetNames() []string {
+	var names []string
+	for _, s := range wb.sheets {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// Rows はシートのセル値を行ごとに返す。name が空なら先頭のシートを読む。
+// 空のセルや行は空文字で埋める。
+func (wb *Workbook) Rows(name string) ([][]string, error) {
+	if len(wb.sheets) == 0 {
+		return nil, fmt.Errorf("xlsx にシートがありません")
+	}
+	ref := wb.sheets[0]
+	if name != "" {
+		found := false
+		for _, s := range wb.sheets {
+			if s.name == name {
+				ref, found = s, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("シート '%s' が見つかりません (%s)", name, strings.Join(wb.SheetNames(), ", "))
+		}
+	}
+
+	var ws struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string    `xml:"r,attr"`
+				Type   string    `xml:"t,attr"`
+				Style  int       `xml:"s,attr"`
+				Value  string    `xml:"v"`
+				Inline *richText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := wb.decode(ref.path, &ws); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, r := range ws.Rows {
+		rowIdx := r.R - 1
+		if r.R == 0 {
+			rowIdx = len(rows)
+		}
+		for len(rows) <= rowIdx {
+			rows = append(rows, nil)
+		}
+
+		var cells []string
+		for i, c := range r.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+
+			switch c.Type {
+			case "s":
+				n, err := strconv.Atoi(c.Value)
+				if err != nil || n < 0 || n >= len(wb.strings) {
+					return nil, fmt.Errorf("%s: 共有文字列の参照が不正です", c.Ref)
+				}
+				cells[col] = wb.strings[n]
+			case "inlineStr":
+				if c.Inline != nil {
+					cells[col] = c.Inline.text()
+				}
+			case "b":
+				if c.Value == "1" {
+					cells[col] = "TRUE"
+				} else {
+					cells[col] = "FALSE"
+				}
+			case "str", "e", "d":
+				cells[col] = c.Value
+			default: // 数値
+				cells[col] = wb.formatNumber(c.Value, c.Style)
+			}
+		}
+		rows[rowIdx] = cells
+	}
+	return rows, nil
+}
+
+// --- ブックの読み込み ---
+
+// richText は共有文字列・インライン文字列。ふりがな (rPh) は読み飛ばす。
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt richText) text() string {
+	if len(rt.Runs) == 0 {
+		return rt.T
+	}
+	var b strings.Builder
+	b.WriteString(rt.T)
+	for _, r := range rt.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+func (wb *Workbook) load() error {
+	var book struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := wb.decode("xl/workbook.xml", &book); err != nil {
+		return err
+	}
+
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := wb.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return err
+	}
+	targets := make(map[string]string)
+	for _, r := range rels.Items {
+		t := r.Target
+		if strings.HasPrefix(t, "/") {
+			t = strings.TrimPrefix(t, "/")
+		} else {
+			t = path.Join("xl", t)
+		}
+		targets[r.ID] = t
+	}
+	for _, s := range book.Sheets {
+		p, ok := targets[s.RID]
+		if !ok {
+			return fmt.Errorf("シート '%s' の参照先が見つかりません", s.Name)
+		}
+		wb.sheets = append(wb.sheets, sheetRef{name: s.Name, path: p})
+	}
+
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := wb.decode("xl/sharedStrings.xml", &sst); err != nil && !isNotFound(err) {
+		return err
+	}
+	for _, si := range sst.Items {
+		wb.strings = append(wb.strings, si.text())
+	}
+
+	return wb.loadStyles()
+}
+
+// loadStyles はセルのスタイルごとの数値の表示形式を読み込む。
+func (wb *Workbook) loadStyles() error {
+	var st struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		Xfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := wb.decode("xl/styles.xml", &st); err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	codes := make(map[int]string)
+	for _, f := range st.NumFmts {
+		codes[f.ID] = f.Code
+	}
+	for _, xf := range st.Xfs {
+		wb.styles = append(wb.styles, codes[xf.NumFmtID])
+	}
+	return nil
+}
+
+type notFoundError struct{ name string }
+
+func (e notFoundError) Error() string { return "xlsx 内に " + e.name + " がありません" }
+
+func isNotFound(err error) bool {
+	_, ok := err.(notFoundError)
+	return ok
+}
+
+func (wb *Workbook) decode(name string, v any) error {
+	for _, f := range wb.zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s を開けません: %w", name, err)
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(io.Reader(rc)).Decode(v); err != nil {
+			return fmt.Errorf("%s の解析に失敗: %w", name, err)
+		}
+		return nil
+	}
+	return notFoundError{name}
+}
+
+// --- 数値の書式 ---
+
+// formatNumber は数値セルを文字列にする。整数で表示形式がゼロ埋めの場合は桁を補う。
+func (wb *Workbook) formatNumber(v string, style int) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+
+	if style < 0 || style >= len(wb.styles) || strings.Contains(s, ".") || f < 0 {
+		return s
+	}
+	if pattern := zeroPattern(wb.styles[style]); pattern != "" {
+		return applyZeroPattern(s, pattern)
+	}
+	return s
+}
+
+// zeroPattern は「0000000」「000-0000」のようなゼロ埋めの表示形式から
+// 0 と区切り記号だけのパターンを取り出す。該当しなければ空文字。
+// 条件付きの複数セクション ([<=999]000;...) は最も長いセクションを使う。
+func zeroPattern(code string) string {
+	best := ""
+	for _, section := range strings.Split(code, ";") {
+		var b strings.Builder
+		zeros := 0
+		inQuote := false
+		inBracket := false
+		escaped := false
+		ok := true
+		for _, r := range section {
+			switch {
+			case escaped:
+				b.WriteRune(r)
+				escaped = false
+			case inBracket:
+				inBracket = r != ']'
+			case inQuote:
+				if r == '"' {
+					inQuote = false
+				} else {
+					b.WriteRune(r)
+				}
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				inQuote = true
+			case r == '[':
+				inBracket = true
+			case r == '0':
+				b.WriteRune(r)
+				zeros++
+			case r == '-' || r == ' ':
+				b.WriteRune(r)
+			default:
+				ok = false
+			}
+		}
+		if ok && zeros > 1 && len(b.String()) > len(best) {
+			best = b.String()
+		}
+	}
+	return best
+}
+
+// applyZeroPattern は数字列を右から順にパターンの 0 に当てはめ、足りない桁を 0 で補う。
+func applyZeroPattern(digits, pattern string) string {
+	p := []rune(pattern)
+	d := []rune(digits)
+	out := make([]rune, len(p))
+	di := len(d) - 1
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i] != '0' {
+			out[i] = p[i]
+			continue
+		}
+		if di >= 0 {
+			out[i] = d[di]
+			di--
+		} else {
+			out[i] = '0'
+		}
+	}
+	if di >= 0 { // パターンより桁が多い場合は先頭に残りを付ける
+		return string(d[:di+1]) + string(out)
+	}
+	return string(out)
+}
+
+// columnIndex は「C5」のようなセル参照から列位置 (0-indexed) を返す。
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
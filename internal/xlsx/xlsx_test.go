@@ -0,0 +1,177 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testWorkbook = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="メモ" sheetId="1" r:id="rId1"/><sheet name="住所録" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`
+
+const testRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`
+
+const testSharedStrings = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>姓</t></si>
+<si><t>郵便番号</t></si>
+<si><r><t>佐</t></r><r><t>藤</t></r><rPh><t>サトウ</t></rPh></si>
+</sst>`
+
+// スタイル 1: 0000000、2: 000-0000、3: "〒"000-0000、4: 標準
+const testStyles = `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts>
+<numFmt numFmtId="164" formatCode="0000000"/>
+<numFmt numFmtId="165" formatCode="000\-0000"/>
+<numFmt numFmtId="166" formatCode="&quot;〒&quot;000-0000"/>
+</numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="165"/><xf numFmtId="166"/><xf numFmtId="0"/></cellXfs>
+</styleSheet>`
+
+const testSheet1 = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>メモ</t></is></c></row></sheetData>
+</worksheet>`
+
+const testSheet2 = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>送付</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" s="1"><v>600001</v></c><c r="C2" t="b"><v>1</v></c></row>
+<row r="4"><c r="A4" t="str"><v>鈴木</v></c><c r="B4" s="2"><v>5300001</v></c><c r="C4" t="b"><v>0</v></c></row>
+<row r="5"><c r="B5" s="3"><v>40001</v></c><c r="D5" s="4"><v>12.5</v></c></row>
+</sheetData>
+</worksheet>`
+
+func writeTestXLSX(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.xlsx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testFiles() map[string]string {
+	return map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testRels,
+		"xl/sharedStrings.xml":       testSharedStrings,
+		"xl/styles.xml":              testStyles,
+		"xl/worksheets/sheet1.xml":   testSheet1,
+		"xl/worksheets/sheet2.xml":   testSheet2,
+	}
+}
+
+func TestRows(t *testing.T) {
+	wb, err := Open(writeTestXLSX(t, testFiles()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+
+	if got, want := wb.SheetNames(), []string{"メモ", "住所録"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SheetNames = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		sheet string
+		want  [][]string
+	}{
+		{"", [][]string{{"メモ"}}},
+		{"メモ", [][]string{{"メモ"}}},
+		{"住所録", [][]string{
+			{"姓", "郵便番号", "送付"},
+			{"佐藤", "0600001", "TRUE"},
+			nil,
+			{"鈴木", "530-0001", "FALSE"},
+			{"", "〒004-0001", "", "12.5"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := wb.Rows(tt.sheet)
+		if err != nil {
+			t.Errorf("Rows(%q): %v", tt.sheet, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Rows(%q) = %q, want %q", tt.sheet, got, tt.want)
+		}
+	}
+
+	_, err = wb.Rows("無い")
+	if err == nil || !strings.Contains(err.Error(), "メモ, 住所録") {
+		t.Errorf("Rows(無い) = %v, want シート名の一覧を含むエラー", err)
+	}
+}
+
+func TestRowsWithoutOptionalParts(t *testing.T) {
+	files := testFiles()
+	delete(files, "xl/sharedStrings.xml")
+	delete(files, "xl/styles.xml")
+	files["xl/worksheets/sheet2.xml"] = `<worksheet><sheetData><row><c><v>600001</v></c><c t="inlineStr"><is><t>x</t></is></c></row></sheetData></worksheet>`
+
+	wb, err := Open(writeTestXLSX(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+	got, err := wb.Rows("住所録")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"600001", "x"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rows = %q, want %q", got, want)
+	}
+}
+
+func TestZeroPattern(t *testing.T) {
+	tests := []struct {
+		code, digits, want string
+	}{
+		{"0000000", "600001", "0600001"},
+		{`000\-0000`, "600001", "060-0001"},
+		{`"〒"000-0000`, "40001", "〒004-0001"},
+		{"[<=999]000;000-0000", "1000001", "100-0001"},
+		{"General", "600001", ""},
+		{"0", "600001", ""},
+		{"#,##0", "600001", ""},
+	}
+	for _, tt := range tests {
+		pattern := zeroPattern(tt.code)
+		got := ""
+		if pattern != "" {
+			got = applyZeroPattern(tt.digits, pattern)
+		}
+		if got != tt.want {
+			t.Errorf("%s に %s = %q, want %q", tt.code, tt.digits, got, tt.want)
+		}
+	}
+}
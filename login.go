@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/sheets"
+)
+
+func cmdLogin(args []string) {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	noBrowser := fs.Bool("no-browser", false, "ブラウザを開かず URL を表示するだけにする")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	if cfg.OAuthClientFile == "" {
+		exitError(fmt.Errorf("oauth_client_file が設定されていません (サービスアカウントを使う場合は login は不要です)"))
+	}
+
+	open := openBrowser
+	if *noBrowser {
+		open = nil
+	}
+	email, path, err := sheets.Login(context.Background(), cfg.OAuthClientFile, cfg.Endpoints, open, os.Stdout)
+	if err != nil {
+		exitError(err)
+	}
+	if email == "" {
+		email = "Google アカウント"
+	}
+	fmt.Printf("%s でログインしました。ログイン情報は %s に保存しました。\n", email, path)
+}
+
+func cmdLogout(args []string) {
+	fs := flag.NewFlagSet("logout", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	if cfg.OAuthClientFile == "" {
+		exitError(fmt.Errorf("oauth_client_file が設定されていません"))
+	}
+
+	ok, err := sheets.Logout(cfg.OAuthClientFile, cfg.Endpoints, os.Stderr)
+	if err != nil {
+		exitError(err)
+	}
+	if !ok {
+		fmt.Println("ログインしていません。")
+		return
+	}
+	fmt.Println("ログアウトしました。")
+}
+
+// openBrowser は url を既定のブラウザで開く。
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return errors.New("ブラウザを開けません")
+		}
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
@@ -4,11 +4,16 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"atena_printer/internal/config"
+	"atena_printer/internal/filter"
+	"atena_printer/internal/journal"
+	"atena_printer/internal/manifest"
 	"atena_printer/internal/model"
 	"atena_printer/internal/pdf"
-	"atena_printer/internal/sheets"
+	"atena_printer/internal/postal"
+	"atena_printer/internal/source"
 )
 
 func main() {
@@ -25,8 +30,42 @@ func main() {
 		cmdGenerate(args)
 	case "mark-sent":
 		cmdMarkSent(args)
+	case "mark-received":
+		cmdMarkReceived(args)
 	case "list":
 		cmdList(args)
+	case "stats":
+		cmdStats(args)
+	case "undo":
+		cmdUndo(args)
+	case "export":
+		cmdExport(args)
+	case "import":
+		cmdImport(args)
+	case "add":
+		cmdAdd(args)
+	case "edit":
+		cmdEdit(args)
+	case "remove":
+		cmdRemove(args)
+	case "show":
+		cmdShow(args)
+	case "db-import":
+		cmdDBImport(args)
+	case "assign-ids":
+		cmdAssignIDs(args)
+	case "new-year":
+		cmdNewYear(args)
+	case "check-postal":
+		cmdCheckPostal(args)
+	case "complete":
+		cmdComplete(args)
+	case "login":
+		cmdLogin(args)
+	case "logout":
+		cmdLogout(args)
+	case "fake-sheets":
+		cmdFakeSheets(args)
 	case "help":
 		printUsage()
 	default:
@@ -43,25 +82,136 @@ func printUsage() {
   atena_printer <command> [options]
 
 コマンド:
-  generate     宛名PDFを生成する
-  mark-sent    印刷済みの宛先をスプレッドシートに記録する
-  list         住所一覧とステータスを表示する
-  help         この使い方を表示する
+  generate       宛名PDFを生成する
+  mark-sent      印刷済みの宛先をスプレッドシートに記録する
+  mark-received  届いた年賀状の差出人をスプレッドシートに記録する
+  list           住所一覧とステータスを表示する
+  stats          年ごとの送受信件数・片方向の相手・削除候補を集計する
+  undo           mark-sent などの書き込みを取り消す
+  export         住所録を vCard (.vcf) に書き出す
+  import         筆まめ・筆ぐるめ・宛名職人の CSV を TSV に変換する
+  add            宛先を追加する (SQLite)
+  edit           宛先を編集する (SQLite)
+  remove         宛先を削除する (SQLite)
+  show           宛先の詳細と年ごとの記録を表示する (SQLite)
+  db-import      TSV・シートなどの住所録を SQLite に取り込む
+  assign-ids     ID列が空の行にIDを割り当てる
+  new-year       その年の YYYY送/受/喪中 列を前の年の列の隣に追加する
+  check-postal   郵便番号と住所が一致しているか郵便番号データで確認する
+  complete       空の郵便番号を住所から、空の住所を郵便番号から郵便番号データで補完する
+  login          Google アカウントでログインする (oauth_client_file を使う場合)
+  logout         ログインを取り消し、保存したログイン情報を削除する
+  fake-sheets    TSV を Google Sheets API に見立てたテスト用サーバを起動する
+  help           この使い方を表示する
 
 共通オプション:
   -config string  設定ファイルのパス (default: config.json)
 
 generate オプション:
   -all           喪中・送付済みを含めて全件出力する
+  -mode string   出力の種類 (default: nenga)
+                   nenga: 未送付かつ喪中でない宛先に年賀状
+                   reply: 受け取ったが送っていない宛先に寒中見舞い
+                   mourning-notice: 過去 -years 年に送受信のあった宛先に喪中はがき
+  -years int     mourning-notice で遡る年数 (default: 1)
+  -where string  絞り込み式 (下記参照)
+  -sent / -received / -mourning yes|no
+                 対象年のステータスで絞り込む (-mode の条件を上書き)
   -output string 出力ファイルパス (設定ファイルの値を上書き)
+  -back string   裏面(文面)PDFの出力パス (-mode に対応する back_templates を使用)
+  -manifest string
+                 印刷記録(マニフェスト)の出力パス (default: 出力PDFと同名の .manifest.json)
 
 mark-sent オプション:
   -dry-run       実際には書き込まず対象を表示する
+  -where string  絞り込み式 (-manifest とは併用不可)
+  -manifest string
+                 generate が出力したマニフェストに含まれる宛先だけを更新する
+
+mark-received オプション:
+  -dry-run       実際には書き込まず対象を表示する
+  -file string   名前を1行ずつ書いたファイル
+  (名前は引数で指定。引数も -file も無い場合は標準入力から読む)
+
+list オプション:
+  -where string  絞り込み式
+
+stats オプション:
+  -years int     片方向・削除候補の判定に使う年数 (default: 3)
+  -format string 出力形式 text / tsv / json (default: text)
+  -table string  tsv で出力する表 people / years (default: people)
+
+undo オプション:
+  -list          書き込み記録の一覧を表示する
+  -id string     取り消す操作のID (default: 取り消していない最後の操作)
+  -dry-run       実際には書き込まず戻す内容を表示する
+
+export オプション:
+  -format string 出力形式 (default: vcf)
+  -where string  絞り込み式
+  -output string 出力ファイルパス (default: 標準出力)
+
+import オプション:
+  -from string   取り込み元の形式 fudemame / fudegurume / atenashokunin (default: 自動判定)
+  -output string 書き出す TSV ファイルのパス (省略時は列の対応と先頭数件のプレビューのみ)
+  -preview int   プレビューに表示する件数 (default: 3)
+
+add / edit オプション (edit は ID か名前を引数で指定し、指定した項目だけ変更する):
+  -family / -given / -reading / -honorific string
+  -joint string  連名 (カンマ区切り)
+  -postal / -address1 / -address2 string
+  -tags string   タグ (カンマ区切り)
+
+remove オプション:
+  -yes           確認せずに削除する
+
+db-import オプション:
+  -append        登録済みの宛先があっても追加する
+  (取り込み元はファイルを引数で指定。省略時は設定の tsv_file / address_file / spreadsheet_id)
+
+assign-ids オプション:
+  -dry-run       実際には書き込まず件数を表示する
+
+new-year オプション:
+  -year int      追加する年 (default: 設定ファイルの year)
+  -copy-format   前の年の列から入力規則 (チェックボックスなど) と書式をコピーする (default: true)
+  -dry-run       実際には追加せず追加する列を表示する
+
+check-postal オプション:
+  -where string  絞り込み式
+
+complete オプション:
+  -where string  絞り込み式
+  -dry-run       実際には書き込まず補完する内容を表示する
+  -yes           確認せずに書き込む
+
+login オプション:
+  -no-browser    ブラウザを開かず URL を表示するだけにする
+
+fake-sheets オプション:
+  -tsv string    シートの内容として使う TSV ファイル (必須)
+  -sheet string  シート名 (default: 住所録)
+  -addr string   待ち受けるアドレス (default: 127.0.0.1:8089)
+  -write-credentials string
+                 このサーバ用のサービスアカウント認証ファイルを書き出す
+  -write-oauth-client string
+                 このサーバの login 用の OAuth クライアントファイルを書き出す
+  -fail-rate float
+                 503 で失敗させるリクエストの割合 (0〜1)
+
+絞り込み式 (-where):
+  sent / received / mourning      対象年のステータス (sent(2025) で年を指定)
+  tag:値 / pref:値 / honorific:値 タグ列・都道府県・敬称
+  !, &&, ||, ( ) で組み合わせる   例: 'tag:仕事 && !sent && received(2025)'
 
 補足:
-  tsv_file が設定されている場合はローカルTSV読み取りモードになります。
+  sqlite_file が設定されている場合は SQLite の住所録を読み書きします。
+  address_file が設定されている場合は CSV / TSV / Excel / vCard ファイルを読み込みます (読み取り専用)。
+  tsv_file が設定されている場合はローカルTSVモードになり、TSVファイルを直接更新します。
+  oauth_client_file を設定した場合は login でログインした Google アカウントでシートを読み書きします。
   それ以外で credentials_file が空の場合は公開シート読み取りモードになり、
-  generate / list のみ利用できます。
+  generate / list / stats のみ利用できます。
+  endpoints に URL を設定すると Google の代わりに fake-sheets などのサーバに接続します。
 `)
 }
 
@@ -69,9 +219,28 @@ func cmdGenerate(args []string) {
 	fs := flag.NewFlagSet("generate", flag.ExitOnError)
 	configPath := fs.String("config", "config.json", "設定ファイルのパス")
 	all := fs.Bool("all", false, "全件出力する")
+	modeName := fs.String("mode", "nenga", generateModeUsage())
+	sf := addStatusFlags(fs)
+	years := fs.Int("years", 1, "mourning-notice で遡る年数")
+	whereExpr := fs.String("where", "", "絞り込み式 (例: 'tag:仕事 && !sent')")
 	output := fs.String("output", "", "出力ファイルパス")
+	backPath := fs.String("back", "", "裏面PDFの出力パス")
+	manifestPath := fs.String("manifest", "", "マニフェストの出力パス")
 	fs.Parse(args)
 
+	mode, ok := generateModes[*modeName]
+	if !ok {
+		exitError(fmt.Errorf("不明な -mode: %s (%s のいずれかを指定してください)", *modeName, generateModeNames()))
+	}
+	cond, err := sf.apply(mode.filter)
+	if err != nil {
+		exitError(err)
+	}
+	where, err := filter.Parse(*whereExpr)
+	if err != nil {
+		exitError(err)
+	}
+
 	cfg, err := config.Load(*configPath)
 	if err != nil {
 		exitError(err)
@@ -79,13 +248,16 @@ func cmdGenerate(args []string) {
 	if *output != "" {
 		cfg.OutputFile = *output
 	}
+	if *manifestPath == "" {
+		*manifestPath = manifest.PathFor(cfg.OutputFile)
+	}
 
-	client, err := sheets.New(cfg.CredentialsFile, cfg.SpreadsheetID, cfg.SheetName, cfg.TSVFile)
+	client, err := source.Open(cfg)
 	if err != nil {
 		exitError(err)
 	}
 
-	addresses, statuses, err := client.ReadAddresses(cfg.Year)
+	addresses, histories, err := client.ReadHistory()
 	if err != nil {
 		exitError(err)
 	}
@@ -93,20 +265,51 @@ func cmdGenerate(args []string) {
 	// フィルタリング
 	var targets []model.Address
 	for _, addr := range addresses {
-		st := statuses[addr.Row]
+		h := histories[addr.Row]
 		if !*all {
-			if st.Sent || st.Mourning {
+			if mode.exchanged && !exchangedWithin(h, cfg.Year, *years) {
 				continue
 			}
+			if !cond.match(h[cfg.Year]) {
+				continue
+			}
+		}
+		if !where.Eval(filter.Env{Address: addr, History: h, Year: cfg.Year}) {
+			continue
 		}
 		targets = append(targets, addr)
 	}
 
+	// 郵便番号が空の宛先は (住所が未入力のことが多いため) 警告して飛ばし、
+	// 7桁の数字でない宛先があれば、PDF を作る前にまとめて知らせる
+	var invalid, skipped []string
+	printable := targets[:0]
+	for _, addr := range targets {
+		switch {
+		case addr.PostalCode == "":
+			skipped = append(skipped, fmt.Sprintf("  %s %s", addr.FamilyName, addr.GivenName))
+		case !postal.Valid(addr.PostalCode):
+			invalid = append(invalid, fmt.Sprintf("  %s %s: 〒%s", addr.FamilyName, addr.GivenName, addr.PostalCode))
+		default:
+			printable = append(printable, addr)
+		}
+	}
+	if len(invalid) > 0 {
+		fmt.Fprintf(os.Stderr, "郵便番号が7桁の数字でない宛先があります:\n%s\n", strings.Join(invalid, "\n"))
+		exitError(fmt.Errorf("郵便番号を修正するか、-where で除外してください (%d件)", len(invalid)))
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "警告: 郵便番号が空の宛先は出力しません (%d件):\n%s\n", len(skipped), strings.Join(skipped, "\n"))
+	}
+	targets = printable
+
 	if len(targets) == 0 {
 		fmt.Println("出力対象の宛先がありません。")
 		return
 	}
 
+	warnPostalMismatches(cfg, targets)
+
 	gen, err := pdf.NewGenerator(cfg.FontFile, cfg.PostalFontFile, cfg.Sender)
 	if err != nil {
 		exitError(err)
@@ -123,87 +326,255 @@ func cmdGenerate(args []string) {
 	}
 
 	fmt.Printf("PDF を生成しました: %s (%d件)\n", cfg.OutputFile, len(targets))
+
+	if *backPath != "" {
+		tmpl, ok := cfg.BackTemplates[*modeName]
+		if !ok {
+			exitError(fmt.Errorf("back_templates に %s の文面が設定されていません", *modeName))
+		}
+		back, err := pdf.NewGenerator(cfg.FontFile, "", cfg.Sender)
+		if err != nil {
+			exitError(err)
+		}
+		back.AddBackPage(tmpl, cfg.Year)
+		if err := back.Save(*backPath); err != nil {
+			exitError(fmt.Errorf("裏面 PDF の保存に失敗: %w", err))
+		}
+		fmt.Printf("裏面 PDF を生成しました: %s\n", *backPath)
+	}
+
+	if mode.noMark {
+		fmt.Printf("-mode %s は年賀状ではないためマニフェストは出力しません。\n", *modeName)
+		return
+	}
+
+	m := manifest.New(cfg.Year, cfg.OutputFile, targets)
+	m.Mode = *modeName
+	if err := m.Save(*manifestPath); err != nil {
+		exitError(fmt.Errorf("マニフェストの保存に失敗: %w", err))
+	}
+	fmt.Printf("マニフェストを保存しました: %s\n", *manifestPath)
 }
 
 func cmdMarkSent(args []string) {
 	fs := flag.NewFlagSet("mark-sent", flag.ExitOnError)
 	configPath := fs.String("config", "config.json", "設定ファイルのパス")
 	dryRun := fs.Bool("dry-run", false, "実際には書き込まず対象を表示する")
+	manifestPath := fs.String("manifest", "", "generate が出力したマニフェストのパス")
+	whereExpr := fs.String("where", "", "絞り込み式 (例: 'tag:仕事')")
 	fs.Parse(args)
 
-	cfg, err := config.Load(*configPath)
+	if *manifestPath != "" && *whereExpr != "" {
+		exitError(fmt.Errorf("-manifest と -where は同時に指定できません"))
+	}
+	where, err := filter.Parse(*whereExpr)
 	if err != nil {
 		exitError(err)
 	}
-	if cfg.TSVFile != "" {
-		exitError(fmt.Errorf("mark-sent は tsv_file 使用時は利用できません"))
-	}
-	if cfg.CredentialsFile == "" {
-		exitError(fmt.Errorf("mark-sent は credentials_file 設定時のみ利用できます"))
-	}
-	if cfg.SpreadsheetID == "" {
-		exitError(fmt.Errorf("mark-sent には spreadsheet_id が必要です"))
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
 	}
 
-	client, err := sheets.New(cfg.CredentialsFile, cfg.SpreadsheetID, cfg.SheetName, cfg.TSVFile)
+	src, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+	client, err := source.Writer(src, "mark-sent")
 	if err != nil {
 		exitError(err)
 	}
 
-	addresses, statuses, err := client.ReadAddresses(cfg.Year)
+	addresses, histories, err := client.ReadHistory()
 	if err != nil {
 		exitError(err)
 	}
+	statuses := yearStatuses(histories, cfg.Year)
 
-	var rows []int
-	for _, addr := range addresses {
-		st := statuses[addr.Row]
-		if !st.Sent && !st.Mourning {
-			rows = append(rows, addr.Row)
+	var targets []model.Address
+	if *manifestPath != "" {
+		m, err := manifest.Load(*manifestPath)
+		if err != nil {
+			exitError(err)
+		}
+		targets, err = manifestTargets(m, cfg.Year, addresses, statuses)
+		if err != nil {
+			exitError(err)
+		}
+	} else {
+		var skipped []string
+		for _, addr := range addresses {
+			st := statuses[addr.Row]
+			env := filter.Env{Address: addr, History: histories[addr.Row], Year: cfg.Year}
+			if st.Sent || st.Mourning || !where.Eval(env) {
+				continue
+			}
+			// generate が印刷しない (郵便番号が空・7桁の数字でない) 宛先は送付済みにしない
+			if !postal.Valid(addr.PostalCode) {
+				skipped = append(skipped, fmt.Sprintf("  %s %s", addr.FamilyName, addr.GivenName))
+				continue
+			}
+			targets = append(targets, addr)
 			fmt.Printf("  %s %s (%s)\n", addr.FamilyName, addr.GivenName, addr.Address1)
 		}
+		if len(skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "警告: 郵便番号が空か7桁の数字でない宛先は印刷されないため更新しません (%d件):\n%s\n", len(skipped), strings.Join(skipped, "\n"))
+		}
 	}
 
-	if len(rows) == 0 {
+	if len(targets) == 0 {
 		fmt.Println("更新対象がありません。")
 		return
 	}
 
 	if *dryRun {
-		fmt.Printf("\n%d件が対象です (dry-run: 書き込みはしません)\n", len(rows))
+		fmt.Printf("\n%d件が対象です (dry-run: 書き込みはしません)\n", len(targets))
 		return
 	}
 
-	if err := client.MarkSent(cfg.Year, rows); err != nil {
+	client.SetJournal(journal.Open(cfg.JournalFile), "mark-sent")
+	if err := client.MarkSent(cfg.Year, targets); err != nil {
 		exitError(err)
 	}
 
-	fmt.Printf("\n%d件を %d送 = ○ に更新しました。\n", len(rows), cfg.Year)
+	fmt.Printf("\n%d件を %d年の送付済みに更新しました。\n", len(targets), cfg.Year)
+}
+
+// manifestTargets はマニフェストに記録された宛先のうち、まだ送付済みになっていないものを返す。
+// 印刷後に行の内容が変わっている場合や、送付済みにしないモード (noMark) のマニフェストは
+// 誤記録を避けるためエラーにする。mode の無いマニフェストは nenga として扱う。
+func manifestTargets(m *manifest.Manifest, year int, addresses []model.Address, statuses map[int]model.YearStatus) ([]model.Address, error) {
+	if m.Year != year {
+		return nil, fmt.Errorf("マニフェストの年 (%d) が設定の年 (%d) と一致しません", m.Year, year)
+	}
+	if m.Mode != "" {
+		mode, ok := generateModes[m.Mode]
+		if !ok {
+			return nil, fmt.Errorf("マニフェストの -mode (%s) が不明です (%s のいずれか)", m.Mode, generateModeNames())
+		}
+		if mode.noMark {
+			return nil, fmt.Errorf("マニフェストは -mode %s で出力されたもので、送付済みとしては記録できません", m.Mode)
+		}
+	}
+
+	byRow := make(map[int]model.Address, len(addresses))
+	byID := make(map[string]model.Address, len(addresses))
+	for _, addr := range addresses {
+		byRow[addr.Row] = addr
+		if addr.ID != "" {
+			byID[addr.ID] = addr
+		}
+	}
+
+	var targets []model.Address
+	var changed []string
+	for _, e := range m.Entries {
+		var addr model.Address
+		var ok bool
+		if e.ID != "" {
+			addr, ok = byID[e.ID]
+		} else {
+			addr, ok = byRow[e.Row]
+		}
+		if !ok {
+			changed = append(changed, fmt.Sprintf("  %d行目 %s: 行が見つかりません", e.Row, e.Name))
+			continue
+		}
+		if manifest.Hash(addr) != e.Hash {
+			changed = append(changed, fmt.Sprintf("  %d行目 %s: 印刷後に内容が変更されています (現在: %s %s)",
+				e.Row, e.Name, addr.FamilyName, addr.GivenName))
+			continue
+		}
+		if statuses[addr.Row].Sent {
+			fmt.Printf("  %s %s (%s) は記録済みのためスキップします\n", addr.FamilyName, addr.GivenName, addr.Address1)
+			continue
+		}
+		targets = append(targets, addr)
+		fmt.Printf("  %s %s (%s)\n", addr.FamilyName, addr.GivenName, addr.Address1)
+	}
+
+	if len(changed) > 0 {
+		return nil, fmt.Errorf("マニフェスト (%s 生成) と現在のシートが一致しません。generate をやり直してください:\n%s",
+			m.GeneratedAt.Format("2006-01-02 15:04"), strings.Join(changed, "\n"))
+	}
+	return targets, nil
+}
+
+func cmdAssignIDs(args []string) {
+	fs := flag.NewFlagSet("assign-ids", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	dryRun := fs.Bool("dry-run", false, "実際には書き込まず件数を表示する")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+
+	src, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+	client, err := source.Writer(src, "assign-ids")
+	if err != nil {
+		exitError(err)
+	}
+
+	client.SetJournal(journal.Open(cfg.JournalFile), "assign-ids")
+	n, err := client.AssignIDs(*dryRun)
+	if err != nil {
+		exitError(err)
+	}
+
+	if n == 0 {
+		fmt.Println("ID が空の行はありません。")
+		return
+	}
+	if *dryRun {
+		fmt.Printf("%d件に ID を割り当てます (dry-run: 書き込みはしません)\n", n)
+		return
+	}
+	fmt.Printf("%d件に ID を割り当てました。\n", n)
 }
 
 func cmdList(args []string) {
 	fs := flag.NewFlagSet("list", flag.ExitOnError)
 	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	whereExpr := fs.String("where", "", "絞り込み式 (例: 'tag:家族 || pref:東京都')")
 	fs.Parse(args)
 
+	where, err := filter.Parse(*whereExpr)
+	if err != nil {
+		exitError(err)
+	}
+
 	cfg, err := config.Load(*configPath)
 	if err != nil {
 		exitError(err)
 	}
 
-	client, err := sheets.New(cfg.CredentialsFile, cfg.SpreadsheetID, cfg.SheetName, cfg.TSVFile)
+	client, err := source.Open(cfg)
 	if err != nil {
 		exitError(err)
 	}
 
-	addresses, statuses, err := client.ReadAddresses(cfg.Year)
+	all, histories, err := client.ReadHistory()
 	if err != nil {
 		exitError(err)
 	}
 
+	var addresses []model.Address
+	for _, addr := range all {
+		if where.Eval(filter.Env{Address: addr, History: histories[addr.Row], Year: cfg.Year}) {
+			addresses = append(addresses, addr)
+		}
+	}
+
+	fmt.Printf("データソース: %s\n", client.Describe())
 	fmt.Printf("--- %d年 住所一覧 (%d件) ---\n", cfg.Year, len(addresses))
 	for _, addr := range addresses {
-		st := statuses[addr.Row]
+		st := histories[addr.Row][cfg.Year]
 		sentMark := " "
 		recvMark := " "
 		mournMark := " "
@@ -222,19 +593,30 @@ func cmdList(args []string) {
 			joint = " ほか"
 		}
 
-		fmt.Printf("  [送:%s 受:%s %s] %s %s%s%s  〒%s %s%s\n",
+		tags := ""
+		if len(addr.Tags) > 0 {
+			tags = "  #" + strings.Join(addr.Tags, " #")
+		}
+
+		fmt.Printf("  [送:%s 受:%s %s] %s %s%s%s  〒%s %s%s%s\n",
 			sentMark, recvMark, mournMark,
 			addr.FamilyName, addr.GivenName, joint, addr.Honorific,
 			formatPostalCode(addr.PostalCode),
-			addr.Address1, addr.Address2)
+			addr.Address1, addr.Address2, tags)
 	}
 }
 
-func formatPostalCode(code string) string {
-	if len(code) == 7 {
-		return code[:3] + "-" + code[3:]
+// yearStatuses は行番号ごとの履歴から指定年のステータスを取り出す。
+func yearStatuses(histories map[int]model.History, year int) map[int]model.YearStatus {
+	statuses := make(map[int]model.YearStatus, len(histories))
+	for row, h := range histories {
+		statuses[row] = h[year]
 	}
-	return code
+	return statuses
+}
+
+func formatPostalCode(code string) string {
+	return postal.Format(code)
 }
 
 func exitError(err error) {
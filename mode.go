@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"atena_printer/internal/model"
+)
+
+// statusCond は年ごとのステータス1項目に対する条件。
+type statusCond string
+
+const (
+	condAny statusCond = ""
+	condYes statusCond = "yes"
+	condNo  statusCond = "no"
+)
+
+func (c statusCond) match(v bool) bool {
+	switch c {
+	case condYes:
+		return v
+	case condNo:
+		return !v
+	}
+	return true
+}
+
+// statusFilter は対象年の 送/受/喪中 の組み合わせで宛先を絞り込む。
+type statusFilter struct {
+	sent     statusCond
+	received statusCond
+	mourning statusCond
+}
+
+func (f statusFilter) match(st model.YearStatus) bool {
+	return f.sent.match(st.Sent) && f.received.match(st.Received) && f.mourning.match(st.Mourning)
+}
+
+// generateMode は generate -mode で選べる出力の種類。
+type generateMode struct {
+	filter statusFilter
+	desc   string
+
+	// exchanged が true のモードは、対象年のステータスではなく
+	// 過去 N 年に送受信があったかどうかで宛先を選ぶ。
+	exchanged bool
+	// noMark が true のモードは年賀状ではないため、mark-sent 用のマニフェストを出力しない。
+	noMark bool
+}
+
+var generateModes = map[string]generateMode{
+	"nenga": {
+		filter: statusFilter{sent: condNo, mourning: condNo},
+		desc:   "年賀状: 未送付かつ喪中でない宛先",
+	},
+	// reply で送ったはがきも mark-sent で「送」として記録し、重ねて返信しないようにする
+	"reply": {
+		filter: statusFilter{sent: condNo, received: condYes},
+		desc:   "寒中見舞い・返信: 受け取ったがこちらから送っていない宛先",
+	},
+	"mourning-notice": {
+		desc:      "喪中はがき: 過去 -years 年に送受信のあった宛先",
+		exchanged: true,
+		noMark:    true,
+	},
+}
+
+// exchangedWithin は対象年の前 years 年間に 送 または 受 の記録があるかどうかを返す。
+func exchangedWithin(h model.History, year, years int) bool {
+	for y := year - years; y < year; y++ {
+		if st := h[y]; st.Sent || st.Received {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedModeNames は -mode で選べるモード名を名前順に返す。
+func sortedModeNames() []string {
+	var names []string
+	for name := range generateModes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func generateModeNames() string {
+	return strings.Join(sortedModeNames(), ", ")
+}
+
+// generateModeUsage は -mode フラグの説明 (モードごとの対象の説明付き) を返す。
+func generateModeUsage() string {
+	lines := []string{"出力の種類"}
+	for _, name := range sortedModeNames() {
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, generateModes[name].desc))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// statusFlags は -sent / -received / -mourning フラグの値。
+type statusFlags struct {
+	sent     *string
+	received *string
+	mourning *string
+}
+
+func addStatusFlags(fs *flag.FlagSet) statusFlags {
+	return statusFlags{
+		sent:     fs.String("sent", "", "送付済みで絞り込む (yes / no)"),
+		received: fs.String("received", "", "受け取り済みで絞り込む (yes / no)"),
+		mourning: fs.String("mourning", "", "喪中で絞り込む (yes / no)"),
+	}
+}
+
+// apply はフラグで指定された条件で base を上書きした絞り込み条件を返す。
+func (sf statusFlags) apply(base statusFilter) (statusFilter, error) {
+	for _, p := range []struct {
+		name string
+		val  string
+		dst  *statusCond
+	}{
+		{"sent", *sf.sent, &base.sent},
+		{"received", *sf.received, &base.received},
+		{"mourning", *sf.mourning, &base.mourning},
+	} {
+		switch statusCond(p.val) {
+		case condAny:
+		case condYes, condNo:
+			*p.dst = statusCond(p.val)
+		default:
+			return base, fmt.Errorf("-%s には yes または no を指定してください: %s", p.name, p.val)
+		}
+	}
+	return base, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/source"
+)
+
+func cmdNewYear(args []string) {
+	fs := flag.NewFlagSet("new-year", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	year := fs.Int("year", 0, "追加する年 (default: 設定ファイルの year)")
+	copyFormat := fs.Bool("copy-format", true, "前の年の列から入力規則 (チェックボックスなど) と書式をコピーする")
+	dryRun := fs.Bool("dry-run", false, "実際には追加せず追加する列を表示する")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	if *year == 0 {
+		*year = cfg.Year
+	}
+
+	src, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+	creator, ok := src.(source.YearColumnCreator)
+	if !ok || !src.Capabilities().InsertColumns {
+		if src.Capabilities().Write {
+			fmt.Printf("%s では年ごとの列を追加する必要はありません。\n", src.Describe())
+			return
+		}
+		exitError(fmt.Errorf("new-year は列を追加できるデータソース (credentials_file / oauth_client_file / tsv_file) でのみ使えます (%s)", src.Describe()))
+	}
+
+	plan, err := creator.PlanNewYear(*year)
+	if err != nil {
+		exitError(err)
+	}
+	if len(plan.Headers) == 0 {
+		fmt.Printf("%d年の列は既にあります。\n", *year)
+		return
+	}
+
+	where := "先頭"
+	if plan.After != "" {
+		where = fmt.Sprintf("列「%s」の後ろ", plan.After)
+	}
+	fmt.Printf("%sに %s を追加します。\n", where, strings.Join(plan.Headers, " / "))
+	if *dryRun {
+		fmt.Println("(dry-run: 追加はしません)")
+		return
+	}
+
+	if _, err := creator.NewYear(*year, *copyFormat); err != nil {
+		exitError(err)
+	}
+	fmt.Printf("%d列を追加しました。\n", len(plan.Headers))
+}
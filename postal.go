@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/filter"
+	"atena_printer/internal/journal"
+	"atena_printer/internal/model"
+	"atena_printer/internal/postal"
+	"atena_printer/internal/source"
+)
+
+func cmdCheckPostal(args []string) {
+	fs := flag.NewFlagSet("check-postal", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	whereExpr := fs.String("where", "", "絞り込み式")
+	fs.Parse(args)
+
+	where, err := filter.Parse(*whereExpr)
+	if err != nil {
+		exitError(err)
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	db, err := loadPostalDB(cfg)
+	if err != nil {
+		exitError(err)
+	}
+
+	src, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+	addresses, histories, err := src.ReadHistory()
+	if err != nil {
+		exitError(err)
+	}
+
+	checked, found := 0, 0
+	for _, addr := range addresses {
+		if !where.Eval(filter.Env{Address: addr, History: histories[addr.Row], Year: cfg.Year}) {
+			continue
+		}
+		checked++
+		m := db.Check(addr.PostalCode, addr.Address1+addr.Address2)
+		if m == nil {
+			continue
+		}
+		found++
+		fmt.Printf("%s %s  〒%s %s%s\n", addr.FamilyName, addr.GivenName, formatPostalCode(addr.PostalCode), addr.Address1, addr.Address2)
+		printMismatch(m, "  ")
+	}
+
+	if found == 0 {
+		fmt.Printf("%d件の郵便番号と住所はすべて一致しています。\n", checked)
+		return
+	}
+	fmt.Printf("\n%d件中 %d件の郵便番号と住所が一致しません。\n", checked, found)
+}
+
+func cmdComplete(args []string) {
+	fs := flag.NewFlagSet("complete", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	whereExpr := fs.String("where", "", "絞り込み式")
+	dryRun := fs.Bool("dry-run", false, "実際には書き込まず補完する内容を表示する")
+	yes := fs.Bool("yes", false, "確認せずに書き込む")
+	fs.Parse(args)
+
+	where, err := filter.Parse(*whereExpr)
+	if err != nil {
+		exitError(err)
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+	db, err := loadPostalDB(cfg)
+	if err != nil {
+		exitError(err)
+	}
+
+	src, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+	// 書き込めないデータソースでも -dry-run で補完する内容は確認できる
+	var writer source.StatusWriter
+	if !*dryRun {
+		if writer, err = source.Writer(src, "complete"); err != nil {
+			exitError(err)
+		}
+		if _, ok := writer.(source.AddressFiller); !ok {
+			exitError(fmt.Errorf("complete は %s では使えません", src.Describe()))
+		}
+	}
+
+	addresses, histories, err := src.ReadHistory()
+	if err != nil {
+		exitError(err)
+	}
+
+	var fills []model.AddressFill
+	var unresolved []string
+	for _, addr := range addresses {
+		if !where.Eval(filter.Env{Address: addr, History: histories[addr.Row], Year: cfg.Year}) {
+			continue
+		}
+		fill, problem := completeAddress(db, addr)
+		name := addr.FamilyName + " " + addr.GivenName
+		if problem != "" {
+			unresolved = append(unresolved, fmt.Sprintf("  %s: %s", name, problem))
+		}
+		if fill == nil {
+			continue
+		}
+		fills = append(fills, *fill)
+
+		fmt.Println(name)
+		if fill.PostalCode != "" {
+			fmt.Printf("  郵便番号: (空欄) → %s  (%s%s から)\n", postal.Format(fill.PostalCode), addr.Address1, addr.Address2)
+		}
+		if fill.Address1 != "" {
+			fmt.Printf("  住所1:    (空欄) → %s  (〒%s から)\n", fill.Address1, postal.Format(addr.PostalCode))
+		}
+		if fill.Address2 != "" {
+			fmt.Printf("  住所2:    (空欄) → %s\n", fill.Address2)
+		}
+	}
+
+	if len(unresolved) > 0 {
+		fmt.Printf("\n補完できなかった宛先 (%d件):\n%s\n", len(unresolved), strings.Join(unresolved, "\n"))
+	}
+	if len(fills) == 0 {
+		fmt.Println("\n補完できる宛先がありません。")
+		return
+	}
+	if *dryRun {
+		fmt.Printf("\n%d件が対象です (dry-run: 書き込みはしません)\n", len(fills))
+		return
+	}
+	if !*yes {
+		fmt.Printf("\n%d件の宛先に書き込みますか? [y/N]: ", len(fills))
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(line)); a != "y" && a != "yes" {
+			fmt.Println("書き込みませんでした。")
+			return
+		}
+	}
+
+	writer.SetJournal(journal.Open(cfg.JournalFile), "complete")
+	if err := writer.(source.AddressFiller).FillAddresses(fills); err != nil {
+		exitError(err)
+	}
+	fmt.Printf("\n%d件を補完しました。郵便番号から補完した住所には番地が含まれないので書き足してください (undo で取り消せます)。\n", len(fills))
+}
+
+// completeAddress は郵便番号が空の宛先は住所から、住所1が空の宛先は郵便番号から補完する値を返す。
+// 補完できない場合は problem に理由を返す (補完の必要が無ければ両方とも空)。
+func completeAddress(db *postal.DB, addr model.Address) (fill *model.AddressFill, problem string) {
+	address := addr.Address1 + addr.Address2
+	switch {
+	case addr.PostalCode == "" && strings.TrimSpace(address) == "":
+		return nil, "郵便番号も住所も空です"
+
+	case addr.PostalCode == "":
+		found := db.Find(address)
+		switch {
+		case len(found) == 0:
+			return nil, "住所に対応する郵便番号が見つかりません"
+		case len(found) > 1:
+			return nil, "住所に対応する郵便番号が複数あります (" + areaList(found) + ")。番地まで確認して入力してください"
+		}
+		return &model.AddressFill{Target: addr, PostalCode: found[0].Code}, ""
+
+	case strings.TrimSpace(addr.Address1) == "":
+		if !postal.Valid(addr.PostalCode) {
+			return nil, fmt.Sprintf("郵便番号 %s が7桁ではありません", addr.PostalCode)
+		}
+		areas := db.Lookup(addr.PostalCode)
+		if len(areas) == 0 {
+			return nil, fmt.Sprintf("郵便番号 %s は郵便番号データにありません", postal.Format(addr.PostalCode))
+		}
+		address1, address2 := areas[0].Address()
+		for _, e := range areas[1:] {
+			if a1, a2 := e.Address(); a1 != address1 || a2 != address2 {
+				return nil, fmt.Sprintf("〒%s の地域が複数あります (%s)", postal.Format(addr.PostalCode), areaList(areas))
+			}
+		}
+		fill := &model.AddressFill{Target: addr, Address1: address1}
+		if strings.TrimSpace(addr.Address2) == "" {
+			fill.Address2 = address2
+		}
+		return fill, ""
+	}
+	return nil, ""
+}
+
+// areaList は郵便番号の地域を「〒123-4567 地域」の形で並べる (多い場合は件数だけ)。
+func areaList(entries []postal.Entry) string {
+	if len(entries) > 5 {
+		return fmt.Sprintf("%d件", len(entries))
+	}
+	var list []string
+	for _, e := range entries {
+		list = append(list, "〒"+postal.Format(e.Code)+" "+e.Area())
+	}
+	return strings.Join(list, ", ")
+}
+
+// printMismatch は郵便番号と住所の食い違いの内容を表示する。
+func printMismatch(m *postal.Mismatch, indent string) {
+	fmt.Printf("%s%s\n", indent, m.Reason)
+	for _, e := range m.Areas {
+		fmt.Printf("%s  〒%s の地域: %s\n", indent, postal.Format(e.Code), e.Area())
+	}
+	switch {
+	case len(m.Suggestions) == 0:
+		fmt.Printf("%s  住所に対応する郵便番号は見つかりませんでした\n", indent)
+	case len(m.Suggestions) > 5:
+		fmt.Printf("%s  住所に対応する郵便番号の候補が %d件あります (住所をより詳しく書くと絞り込めます)\n", indent, len(m.Suggestions))
+	default:
+		for _, e := range m.Suggestions {
+			fmt.Printf("%s  → 住所からは 〒%s (%s)\n", indent, postal.Format(e.Code), e.Area())
+		}
+	}
+}
+
+// loadPostalDB は設定の postal_files から郵便番号データを読み込む。
+func loadPostalDB(cfg *config.Config) (*postal.DB, error) {
+	if len(cfg.PostalFiles) == 0 {
+		return nil, fmt.Errorf("postal_files が設定されていません。日本郵便の KEN_ALL.CSV (と JIGYOSYO.CSV) をダウンロードして指定してください")
+	}
+	return postal.Load(cfg.PostalFiles...)
+}
+
+// warnPostalMismatches は郵便番号と住所が一致しない宛先を警告として表示する (postal_files の設定時のみ)。
+func warnPostalMismatches(cfg *config.Config, targets []model.Address) {
+	if len(cfg.PostalFiles) == 0 {
+		return
+	}
+	db, err := loadPostalDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: 郵便番号の確認をスキップします: %v\n", err)
+		return
+	}
+	var names []string
+	for _, addr := range targets {
+		if db.Check(addr.PostalCode, addr.Address1+addr.Address2) != nil {
+			names = append(names, addr.FamilyName+" "+addr.GivenName)
+		}
+	}
+	if len(names) > 0 {
+		fmt.Fprintf(os.Stderr, "警告: 郵便番号と住所が一致しない宛先があります (%d件: %s)。check-postal で確認してください\n",
+			len(names), strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/journal"
+	"atena_printer/internal/match"
+	"atena_printer/internal/model"
+	"atena_printer/internal/source"
+)
+
+func cmdMarkReceived(args []string) {
+	fs := flag.NewFlagSet("mark-received", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	dryRun := fs.Bool("dry-run", false, "実際には書き込まず対象を表示する")
+	file := fs.String("file", "", "名前を1行ずつ書いたファイル")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+
+	names, fromStdin, err := readNames(fs.Args(), *file)
+	if err != nil {
+		exitError(err)
+	}
+	if len(names) == 0 {
+		exitError(fmt.Errorf("名前が指定されていません"))
+	}
+
+	// 名前を標準入力から読んだ場合、確認の応答は端末から受け取る
+	// (端末は候補の選択が必要になった時に開く)。
+	var prompt io.Reader = os.Stdin
+	if fromStdin {
+		tty := &ttyReader{}
+		defer tty.Close()
+		prompt = tty
+	}
+	answers := bufio.NewReader(prompt)
+
+	src, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+	client, err := source.Writer(src, "mark-received")
+	if err != nil {
+		exitError(err)
+	}
+
+	addresses, statuses, err := client.ReadAddresses(cfg.Year)
+	if err != nil {
+		exitError(err)
+	}
+
+	var targets []model.Address
+	selected := make(map[int]bool)
+	var unmatched []string
+	for _, name := range names {
+		idx, ok := chooseCandidate(name, addresses, answers)
+		if !ok {
+			unmatched = append(unmatched, name)
+			continue
+		}
+		addr := addresses[idx]
+		switch {
+		case selected[idx]:
+			fmt.Printf("  %s → %s %s (指定済み)\n", name, addr.FamilyName, addr.GivenName)
+		case statuses[addr.Row].Received:
+			fmt.Printf("  %s → %s %s (記録済みのためスキップ)\n", name, addr.FamilyName, addr.GivenName)
+		default:
+			fmt.Printf("  %s → %s %s (%s)\n", name, addr.FamilyName, addr.GivenName, addr.Address1)
+			selected[idx] = true
+			targets = append(targets, addr)
+		}
+	}
+
+	if len(unmatched) > 0 {
+		fmt.Printf("\n該当なし・スキップ (%d件): %s\n", len(unmatched), strings.Join(unmatched, ", "))
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("更新対象がありません。")
+		return
+	}
+
+	if *dryRun {
+		fmt.Printf("\n%d件が対象です (dry-run: 書き込みはしません)\n", len(targets))
+		return
+	}
+
+	client.SetJournal(journal.Open(cfg.JournalFile), "mark-received")
+	if err := client.MarkReceived(cfg.Year, targets); err != nil {
+		exitError(err)
+	}
+
+	fmt.Printf("\n%d件を %d年の受取済みに更新しました。\n", len(targets), cfg.Year)
+}
+
+// readNames は引数・ファイル・標準入力の順に名前の一覧を集める。
+// 引数もファイルも無い場合だけ標準入力から読み、その場合は fromStdin が true になる。
+func readNames(args []string, file string) (names []string, fromStdin bool, err error) {
+	names = append(names, args...)
+
+	var r io.Reader
+	switch {
+	case file != "":
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, false, fmt.Errorf("名前ファイルを開けません: %w", err)
+		}
+		defer f.Close()
+		r = f
+	case len(args) == 0:
+		r = os.Stdin
+		fromStdin = true
+	}
+
+	if r != nil {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			names = append(names, line)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, false, fmt.Errorf("名前の読み込みに失敗: %w", err)
+		}
+	}
+	return names, fromStdin, nil
+}
+
+// chooseCandidate は名前に一致する宛先を選ぶ。完全一致が1件ならそのまま確定し、
+// あいまいな場合は候補を表示して番号で選んでもらう。
+func chooseCandidate(name string, addresses []model.Address, answers *bufio.Reader) (int, bool) {
+	cands := match.Find(name, addresses)
+	if len(cands) == 0 {
+		return 0, false
+	}
+	if match.Unique(cands) {
+		return cands[0].Index, true
+	}
+
+	fmt.Printf("\n「%s」の候補:\n", name)
+	for i, c := range cands {
+		addr := addresses[c.Index]
+		fmt.Printf("  %d) %s %s (%s) 〒%s %s\n", i+1,
+			addr.FamilyName, addr.GivenName, c.Matched,
+			formatPostalCode(addr.PostalCode), addr.Address1)
+	}
+	for {
+		fmt.Printf("番号を選択 (0: スキップ): ")
+		line, err := answers.ReadString('\n')
+		n, convErr := strconv.Atoi(strings.TrimSpace(line))
+		if convErr == nil && n >= 0 && n <= len(cands) {
+			if n == 0 {
+				return 0, false
+			}
+			return cands[n-1].Index, true
+		}
+		if errors.Is(err, errNoTTY) {
+			fmt.Println()
+			exitError(err)
+		}
+		if err != nil {
+			return 0, false
+		}
+	}
+}
+
+var errNoTTY = errors.New("確認用の端末を開けません。名前は引数か -file で指定してください")
+
+// ttyReader は最初に読まれた時に /dev/tty を開く io.Reader。
+// 名前を標準入力から読んだ場合に、候補の選択が必要になるまで端末を開かないために使う。
+type ttyReader struct {
+	f   *os.File
+	err error
+}
+
+func (t *ttyReader) Read(p []byte) (int, error) {
+	if t.f == nil && t.err == nil {
+		f, err := os.Open("/dev/tty")
+		if err != nil {
+			t.err = fmt.Errorf("%w: %v", errNoTTY, err)
+		}
+		t.f = f
+	}
+	if t.err != nil {
+		return 0, t.err
+	}
+	return t.f.Read(p)
+}
+
+func (t *ttyReader) Close() error {
+	if t.f == nil {
+		return nil
+	}
+	return t.f.Close()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/source"
+	"atena_printer/internal/stats"
+)
+
+var kindLabels = map[string]string{
+	stats.KindMutual:       "相互",
+	stats.KindSentOnly:     "送のみ",
+	stats.KindReceivedOnly: "受のみ",
+	stats.KindNone:         "なし",
+}
+
+func cmdStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	years := fs.Int("years", 3, "片方向・削除候補の判定に使う年数")
+	format := fs.String("format", "text", "出力形式 (text / tsv / json)")
+	table := fs.String("table", "people", "tsv で出力する表 (people / years)")
+	fs.Parse(args)
+
+	if *years < 1 {
+		exitError(fmt.Errorf("-years には1以上を指定してください"))
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+
+	client, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+
+	addresses, histories, err := client.ReadHistory()
+	if err != nil {
+		exitError(err)
+	}
+
+	report := stats.Build(addresses, histories, cfg.Year, *years)
+
+	switch *format {
+	case "text":
+		printStatsText(report)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			exitError(err)
+		}
+	case "tsv":
+		if err := writeStatsTSV(report, *table); err != nil {
+			exitError(err)
+		}
+	default:
+		exitError(fmt.Errorf("不明な -format: %s (text / tsv / json のいずれかを指定してください)", *format))
+	}
+}
+
+func printStatsText(r *stats.Report) {
+	fmt.Println("--- 年ごとの件数 ---")
+	fmt.Println("  年     送   受   相互 喪中")
+	for _, y := range r.Years {
+		fmt.Printf("  %d %4d %4d %4d %4d\n", y.Year, y.Sent, y.Received, y.Both, y.Mourning)
+	}
+
+	printPeople := func(title string, people []stats.Person) {
+		fmt.Printf("\n--- %s (%d件) ---\n", title, len(people))
+		for _, p := range people {
+			fmt.Printf("  %s %s  送%d年 受%d年  連続 送%d 受%d  最終 送:%s 受:%s\n",
+				p.FamilyName, p.GivenName, p.Sent, p.Received,
+				p.SendStreak, p.ReceiveStreak,
+				yearOrDash(p.LastSent), yearOrDash(p.LastReceived))
+		}
+	}
+
+	period := fmt.Sprintf("%d〜%d年", r.From, r.To)
+	printPeople("こちらからだけ送っている "+period, r.Filter(func(p stats.Person) bool { return p.Kind == stats.KindSentOnly }))
+	printPeople("相手からだけ届いている "+period, r.Filter(func(p stats.Person) bool { return p.Kind == stats.KindReceivedOnly }))
+	printPeople("削除候補: 届いていない・喪中なし "+period, r.Filter(func(p stats.Person) bool { return p.Removal }))
+}
+
+func writeStatsTSV(r *stats.Report, table string) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Comma = '\t'
+
+	switch table {
+	case "years":
+		w.Write([]string{"年", "送", "受", "相互", "喪中"})
+		for _, y := range r.Years {
+			w.Write([]string{
+				strconv.Itoa(y.Year), strconv.Itoa(y.Sent), strconv.Itoa(y.Received),
+				strconv.Itoa(y.Both), strconv.Itoa(y.Mourning),
+			})
+		}
+	case "people":
+		w.Write([]string{"行", "ID", "姓", "名", "送(年数)", "受(年数)", "連続送", "連続受", "最終送", "最終受", "区分", "削除候補"})
+		for _, p := range r.People {
+			removal := ""
+			if p.Removal {
+				removal = "○"
+			}
+			w.Write([]string{
+				strconv.Itoa(p.Row), p.ID, p.FamilyName, p.GivenName,
+				strconv.Itoa(p.Sent), strconv.Itoa(p.Received),
+				strconv.Itoa(p.SendStreak), strconv.Itoa(p.ReceiveStreak),
+				yearOrEmpty(p.LastSent), yearOrEmpty(p.LastReceived),
+				kindLabels[p.Kind], removal,
+			})
+		}
+	default:
+		return fmt.Errorf("不明な -table: %s (people / years のいずれかを指定してください)", table)
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func yearOrDash(y int) string {
+	if y == 0 {
+		return "-"
+	}
+	return strconv.Itoa(y)
+}
+
+func yearOrEmpty(y int) string {
+	if y == 0 {
+		return ""
+	}
+	return strconv.Itoa(y)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"atena_printer/internal/config"
+	"atena_printer/internal/journal"
+	"atena_printer/internal/source"
+)
+
+func cmdUndo(args []string) {
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "設定ファイルのパス")
+	list := fs.Bool("list", false, "書き込み記録の一覧を表示する")
+	id := fs.String("id", "", "取り消す操作のID")
+	dryRun := fs.Bool("dry-run", false, "実際には書き込まず戻す内容を表示する")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		exitError(err)
+	}
+
+	j := journal.Open(cfg.JournalFile)
+
+	if *list {
+		ops, err := j.Operations()
+		if err != nil {
+			exitError(err)
+		}
+		if len(ops) == 0 {
+			fmt.Println("書き込み記録はありません。")
+			return
+		}
+		undone := journal.Undone(ops)
+		for _, op := range ops {
+			note := ""
+			switch {
+			case op.Undoes != "":
+				note = " (" + op.Undoes + " の取り消し)"
+			case undone[op.ID]:
+				note = " [取り消し済み]"
+			}
+			fmt.Printf("  %s  %s  %-13s %3d件  %s%s\n",
+				op.ID, op.Time.Format("2006-01-02 15:04"), op.Command, len(op.Changes), op.Target, note)
+		}
+		return
+	}
+
+	op, err := j.Find(*id)
+	if err != nil {
+		exitError(err)
+	}
+
+	fmt.Printf("操作 %s (%s, %s) を取り消します:\n", op.ID, op.Command, op.Time.Format("2006-01-02 15:04"))
+	for _, ch := range op.Changes {
+		fmt.Printf("  %s: %q → %q\n", ch.Range, ch.New, ch.Old)
+	}
+
+	if *dryRun {
+		fmt.Printf("\n%d件が対象です (dry-run: 書き込みはしません)\n", len(op.Changes))
+		return
+	}
+
+	src, err := source.Open(cfg)
+	if err != nil {
+		exitError(err)
+	}
+	client, err := source.Writer(src, "undo")
+	if err != nil {
+		exitError(err)
+	}
+
+	client.SetJournal(j, "undo")
+	if err := client.Undo(op); err != nil {
+		exitError(err)
+	}
+
+	fmt.Printf("\n%d件を元の値に戻しました。\n", len(op.Changes))
+}